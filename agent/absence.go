@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"sync"
+	"time"
+
+	"gobana-agent/core"
+)
+
+// absenceMonitor track the last entry matching a trigger for each group of "group_by" values,
+// to alert when entries stop arriving and when they resume.
+type absenceMonitor struct {
+	mu      sync.Mutex
+	trigger *TriggerConfigStruct
+	groups  map[string]*absenceGroup
+}
+
+type absenceGroup struct {
+	description string
+	lastSeen    time.Time
+	// absence alert sent, until an entry is seen
+	absent bool
+}
+
+// newAbsenceMonitor create a monitor started at now. Without "group_by", silence since now is alerted,
+// otherwise groups are only monitored once an entry of the group is seen.
+func newAbsenceMonitor(trigger *TriggerConfigStruct, now time.Time) *absenceMonitor {
+	monitor := &absenceMonitor{trigger: trigger, groups: map[string]*absenceGroup{}}
+	if len(trigger.Absence.GroupBy) == 0 {
+		monitor.groups[""] = &absenceGroup{lastSeen: now}
+	}
+	return monitor
+}
+
+// seen record a matching entry. It returns a "back to normal" alert if group was absent, nil otherwise.
+// newAlert is only called for new groups and resumed groups.
+func (monitor *absenceMonitor) seen(entry *core.Entry, now time.Time, newAlert func() *Alert) *Alert {
+	key := triggerGroupKey(entry, monitor.trigger.Absence.GroupBy)
+
+	monitor.mu.Lock()
+	defer monitor.mu.Unlock()
+
+	group, ok := monitor.groups[key]
+	if !ok {
+		group = &absenceGroup{description: triggerGroupDescription(newAlert(), monitor.trigger.Absence.GroupBy)}
+		monitor.groups[key] = group
+	}
+	lastSeen := group.lastSeen
+	group.lastSeen = now
+	if !group.absent {
+		return nil
+	}
+
+	group.absent = false
+	alert := newAlert()
+	alert.Resolved = true
+	alert.LastSeen = lastSeen
+	alert.Interval = monitor.trigger.Absence.Interval
+	alert.Group = group.description
+	return alert
+}
+
+// check returns alerts of groups without matching entry within interval, once per absence.
+func (monitor *absenceMonitor) check(now time.Time) Alerts {
+	monitor.mu.Lock()
+	defer monitor.mu.Unlock()
+
+	var alerts Alerts
+	for _, group := range monitor.groups {
+		if group.absent || now.Sub(group.lastSeen) < monitor.trigger.Absence.Interval {
+			continue
+		}
+		group.absent = true
+		alerts = append(alerts, &Alert{
+			Date:        now,
+			Application: AppConfig.Application,
+			Server:      AppConfig.Server,
+			TriggerName: monitor.trigger.Name,
+			Absent:      true,
+			LastSeen:    group.lastSeen,
+			Interval:    monitor.trigger.Absence.Interval,
+			Group:       group.description,
+		})
+	}
+	return alerts
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"gobana-agent/core"
+)
+
+func TestAbsenceMonitorRedactedGroup(t *testing.T) {
+	tests := map[string]struct {
+		mode  string
+		group string
+	}{
+		"mask": {mode: redactionModeMask, group: "user=[REDACTED:email], _parser=app"},
+		"drop": {mode: redactionModeDrop, group: "user=, _parser=app"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			setTestRedaction(t, test.mode)
+			trigger := &TriggerConfigStruct{
+				Name:    "heartbeat",
+				Absence: &TriggerAbsenceConfigStruct{Interval: time.Minute, GroupBy: []string{"user", "_parser"}},
+			}
+			start := time.Date(2024, 10, 10, 13, 55, 0, 0, time.UTC)
+			monitor := newAbsenceMonitor(trigger, start)
+			entry := &core.Entry{Metadata: core.EntryMetadata{Parser: "app"}, Fields: map[string]string{"user": "bob@example.com"}}
+			newTestAlert := func() *Alert { return newAlert(entry, trigger.Name) }
+
+			if alert := monitor.seen(entry, start, newTestAlert); alert != nil {
+				t.Fatalf("no alert expected for a new group")
+			}
+			if alerts := monitor.check(start.Add(30 * time.Second)); len(alerts) != 0 {
+				t.Fatalf("no alert expected within interval, got %d", len(alerts))
+			}
+
+			alerts := monitor.check(start.Add(2 * time.Minute))
+			if len(alerts) != 1 || !alerts[0].Absent {
+				t.Fatalf("one absence alert expected, got %v", alerts)
+			}
+			if alerts[0].Group != test.group {
+				t.Errorf("absence group = %q, want %q", alerts[0].Group, test.group)
+			}
+			if alerts := monitor.check(start.Add(3 * time.Minute)); len(alerts) != 0 {
+				t.Errorf("absence must be alerted once, got %d alerts", len(alerts))
+			}
+
+			alert := monitor.seen(entry, start.Add(4*time.Minute), newTestAlert)
+			if alert == nil || !alert.Resolved {
+				t.Fatalf("resolved alert expected when entries resume, got %v", alert)
+			}
+			if alert.Group != test.group || !alert.LastSeen.Equal(start) {
+				t.Errorf("resolved group = %q, last seen = %s, want %q, %s", alert.Group, alert.LastSeen, test.group, start)
+			}
+			if alert.Fields["user"] == entry.Fields["user"] {
+				t.Errorf("resolved alert must be redacted, got fields %v", alert.Fields)
+			}
+		})
+	}
+}
@@ -2,8 +2,6 @@ package agent
 
 import (
 	"fmt"
-	"regexp"
-	"strings"
 	"sync"
 	"time"
 
@@ -12,14 +10,6 @@ import (
 
 const (
 	alerterLogPrefix = "alerter"
-
-	triggerTypeRegex        = "match_regex"
-	triggerTypeEqual        = "is"
-	triggerTypeNotEqual     = "is_not"
-	triggerTypeContains     = "contains"
-	triggerTypeNotContains  = "not_contains"
-	triggerTypeStartWith    = "start_with"
-	triggerTypeNotStartWith = "not_start_with"
 )
 
 type Alert struct {
@@ -29,12 +19,45 @@ type Alert struct {
 	Filename    string
 	ParserName  string
 	TriggerName string
+	KeyLine     string
 	Fields      map[string]string
 	Raw         string
+
+	// threshold alerts : number of matching entries within window, group of entries and latest previous entries
+	Count   int
+	Window  time.Duration
+	Group   string
+	Samples Alerts
+
+	// absence alerts : no matching entry since LastSeen (Absent), or entries resumed after LastSeen (Resolved)
+	Absent   bool
+	Resolved bool
+	LastSeen time.Time
+	Interval time.Duration
 }
 
 type Alerts []*Alert
 
+// newAlert create an alert from an entry, sensitive values are redacted before the alert leaves the host.
+func newAlert(entry *core.Entry, triggerName string) *Alert {
+	fields, raw := entry.Fields, entry.Raw
+	if AppConfig.Redaction.redactor.enabled() {
+		fields, raw = AppConfig.Redaction.redactor.redact(fields, raw)
+	}
+
+	return &Alert{
+		Date:        time.Now(),
+		Application: AppConfig.Application,
+		Server:      AppConfig.Server,
+		Filename:    entry.Metadata.Filename,
+		ParserName:  entry.Metadata.Parser,
+		TriggerName: triggerName,
+		KeyLine:     fields[fieldNameKeyLine],
+		Fields:      fields,
+		Raw:         raw,
+	}
+}
+
 func (alerts Alerts) Subject() string {
 	return fmt.Sprintf("%s - %d new alert(s)", AppConfig.Application, len(alerts))
 }
@@ -54,6 +77,8 @@ type AlerterProcess struct {
 	exitChan chan bool
 
 	alertBuffer Alerts
+	thresholds  map[*TriggerConfigStruct]*thresholdCounter
+	absences    map[*TriggerConfigStruct]*absenceMonitor
 }
 
 func (alerter *AlerterProcess) Name() string {
@@ -63,6 +88,14 @@ func (alerter *AlerterProcess) Name() string {
 func (alerter *AlerterProcess) Run() error {
 	alerter.exitChan = make(chan bool)
 
+	// monitor absence triggers from now
+	alerter.absences = map[*TriggerConfigStruct]*absenceMonitor{}
+	for i := range AppConfig.Alerts.Triggers {
+		if trigger := &AppConfig.Alerts.Triggers[i]; trigger.Absence != nil {
+			alerter.absences[trigger] = newAbsenceMonitor(trigger, time.Now())
+		}
+	}
+
 	// subscribe events
 	subscriptionID := core.EventDispatcher.Subscribe(core.EventDescription{
 		Name:     eventNameEntryDiscover,
@@ -72,8 +105,10 @@ func (alerter *AlerterProcess) Run() error {
 	defer core.EventDispatcher.Unsubscribe(subscriptionID)
 
 	core.ProcessInfiniteLoop(time.Duration(AppConfig.Alerts.Frequency)*time.Second, alerter.exitChan, func() {
+		alerter.checkAbsences()
 		// flush pending Alerts
 		alerter.flush()
+		alerter.pruneThresholds()
 	})
 	// execute last flush before exiting
 	alerter.flush()
@@ -111,97 +146,73 @@ func (alerter *AlerterProcess) flush() {
 	}
 }
 
-func HandleParserTrigger(entryObj interface{}) {
-	entry := entryObj.(*core.Entry)
+// thresholdCounter returns the counter of a trigger with threshold.
+func (alerter *AlerterProcess) thresholdCounter(trigger *TriggerConfigStruct) *thresholdCounter {
+	alerter.mu.Lock()
+	defer alerter.mu.Unlock()
 
-	for _, trigger := range AppConfig.Alerts.Triggers {
-		go func(trigger TriggerConfigStruct) {
-			allFieldsMatch := true
-			for _, triggerValue := range trigger.Values {
-				fieldValue := ""
-				switch {
-				case triggerValue.Field == "_parser":
-					fieldValue = entry.Metadata.Parser
-				case triggerValue.Field == "_filename":
-					fieldValue = entry.Metadata.Filename
-				default:
-					if _, ok := entry.Fields[triggerValue.Field]; ok {
-						fieldValue = entry.Fields[triggerValue.Field]
-					} else {
-						core.Logger.Errorf(alerterLogPrefix, "unable to check field value (field \"%s\" not exists)", triggerValue.Field)
-					}
-				}
+	if alerter.thresholds == nil {
+		alerter.thresholds = map[*TriggerConfigStruct]*thresholdCounter{}
+	}
+	counter, ok := alerter.thresholds[trigger]
+	if !ok {
+		counter = newThresholdCounter(trigger.Threshold)
+		alerter.thresholds[trigger] = counter
+	}
+	return counter
+}
 
-				match, err := checkTriggerValueMatch(fieldValue, triggerValue.Operator, triggerValue.Value)
-				if err != nil {
-					core.Logger.Errorf(alerterLogPrefix, "unable to check field value : %s", err)
-					continue
-				}
-				if !match {
-					allFieldsMatch = false
-					break
-				}
-			}
+// pruneThresholds release groups of threshold counters without recent match.
+func (alerter *AlerterProcess) pruneThresholds() {
+	alerter.mu.Lock()
+	counters := make([]*thresholdCounter, 0, len(alerter.thresholds))
+	for _, counter := range alerter.thresholds {
+		counters = append(counters, counter)
+	}
+	alerter.mu.Unlock()
 
-			if allFieldsMatch {
-				core.Logger.Debugf(alerterLogPrefix, "Line match with trigger \"%s\"", trigger.Name)
-				Alerter.addAlert(&Alert{
-					Date:        time.Now(),
-					Application: AppConfig.Application,
-					Server:      AppConfig.Server,
-					Filename:    entry.Metadata.Filename,
-					ParserName:  entry.Metadata.Parser,
-					TriggerName: trigger.Name,
-					Fields:      entry.Fields,
-					Raw:         entry.Raw,
-				})
-			}
-		}(trigger)
+	now := time.Now()
+	for _, counter := range counters {
+		counter.prune(now)
 	}
 }
 
-//nolint:gocyclo
-func checkTriggerValueMatch(fieldValue, operator, operatorValue string) (bool, error) {
-	// special case : ignore empty values
-	if fieldValue == "" {
-		return false, nil
+// checkAbsences add alerts of absence triggers without matching entry within their interval.
+func (alerter *AlerterProcess) checkAbsences() {
+	now := time.Now()
+	for _, monitor := range alerter.absences {
+		for _, alert := range monitor.check(now) {
+			core.Logger.Debugf(alerterLogPrefix, "No entry match with trigger \"%s\" since %s", alert.TriggerName, alert.LastSeen)
+			alerter.addAlert(alert)
+		}
 	}
+}
 
-	lowerFieldValue := strings.ToLower(fieldValue)
-	lowerOperatorValue := strings.ToLower(operatorValue)
+func HandleParserTrigger(entryObj interface{}) {
+	entry := entryObj.(*core.Entry)
 
-	switch operator {
-	case triggerTypeRegex:
-		r := regexp.MustCompile(operatorValue)
-		if r.MatchString(fieldValue) {
-			return true, nil
-		}
-	case triggerTypeEqual:
-		if lowerFieldValue == lowerOperatorValue {
-			return true, nil
-		}
-	case triggerTypeNotEqual:
-		if lowerFieldValue != lowerOperatorValue {
-			return true, nil
-		}
-	case triggerTypeContains:
-		if strings.Contains(lowerFieldValue, lowerOperatorValue) {
-			return true, nil
-		}
-	case triggerTypeNotContains:
-		if !strings.Contains(lowerFieldValue, lowerOperatorValue) {
-			return true, nil
+	for _, trigger := range AppConfig.Alerts.matcher.match(entry) {
+		core.Logger.Debugf(alerterLogPrefix, "Line match with trigger \"%s\"", trigger.Name)
+		if trigger.Absence != nil {
+			if monitor, ok := Alerter.absences[trigger]; ok {
+				if alert := monitor.seen(entry, time.Now(), func() *Alert { return newAlert(entry, trigger.Name) }); alert != nil {
+					core.Logger.Debugf(alerterLogPrefix, "Entries match again with trigger \"%s\"", trigger.Name)
+					Alerter.addAlert(alert)
+				}
+			}
+			continue
 		}
-	case triggerTypeStartWith:
-		if lowerFieldValue[0:len(operatorValue)] == lowerOperatorValue {
-			return true, nil
+		if trigger.Threshold == nil {
+			Alerter.addAlert(newAlert(entry, trigger.Name))
+			continue
 		}
-	case triggerTypeNotStartWith:
-		if lowerFieldValue[0:len(operatorValue)] != lowerOperatorValue {
-			return true, nil
+
+		alert := Alerter.thresholdCounter(trigger).add(entry, time.Now(), func() *Alert {
+			return newAlert(entry, trigger.Name)
+		})
+		if alert != nil {
+			core.Logger.Debugf(alerterLogPrefix, "Threshold of trigger \"%s\" reached (%d entries)", trigger.Name, alert.Count)
+			Alerter.addAlert(alert)
 		}
-	default:
-		return false, fmt.Errorf("unknown trigger operator: %s", operator)
 	}
-	return false, nil
 }
@@ -0,0 +1,175 @@
+package agent
+
+import (
+	"strings"
+	"time"
+
+	"github.com/nxadm/tail"
+)
+
+const (
+	// pending record is flushed when no line is received during this delay
+	recordFlushDelay = 2 * time.Second
+	// records are split when they exceed this number of lines
+	recordMaxLines = 1000
+)
+
+// recordAssembler assemble multi-line records from lines, for parser modes where an entry spans several lines.
+type recordAssembler interface {
+	// push add a line and returns records completed by this line
+	push(line string) []string
+	// flush returns the pending record, if any
+	flush() []string
+}
+
+// newRecordAssembler returns the assembler of parser mode, nil if lines are records.
+func newRecordAssembler(parser *ParserConfigStruct) recordAssembler {
+	switch parser.Mode {
+	case parserModeMySQLSlow:
+		return &blockAssembler{startsRecord: mysqlSlowStartsRecord, ignoreLine: mysqlSlowIgnoreLine}
+	case parserModePostgreSQL:
+		return &blockAssembler{startsRecord: postgresqlStartsRecord}
+	case parserModeAuditd:
+		return &blockAssembler{startsRecord: auditdStartsRecord, endsRecord: auditdEndsRecord}
+	case parserModeJSONStream:
+		return &jsonStreamAssembler{}
+	case parserModeXML:
+		return newXMLStreamAssembler(parser.XMLElement)
+	default:
+		return nil
+	}
+}
+
+// blockAssembler group lines in blocks, a new block begins when startsRecord returns true
+// and ends with the line for which endsRecord returns true (if set).
+type blockAssembler struct {
+	lines        []string
+	startsRecord func(block []string, line string) bool
+	endsRecord   func(line string) bool
+	ignoreLine   func(line string) bool
+}
+
+func (a *blockAssembler) push(line string) []string {
+	if a.ignoreLine != nil && a.ignoreLine(line) {
+		return nil
+	}
+
+	var records []string
+	if len(a.lines) > 0 && (len(a.lines) >= recordMaxLines || a.startsRecord(a.lines, line)) {
+		records = a.flush()
+	}
+	a.lines = append(a.lines, line)
+	if a.endsRecord != nil && a.endsRecord(line) {
+		records = append(records, a.flush()...)
+	}
+
+	return records
+}
+
+func (a *blockAssembler) flush() []string {
+	if len(a.lines) == 0 {
+		return nil
+	}
+	record := strings.Join(a.lines, "\n")
+	a.lines = nil
+	return []string{record}
+}
+
+// jsonStreamAssembler split a stream of json objects regardless of line boundaries,
+// e.g. pretty-printed objects or several objects on a line.
+// Text outside objects is skipped until the next "{", and an object is considered corrupted
+// when a line starts with "{" before its end : it is emitted as is and a new object begins.
+type jsonStreamAssembler struct {
+	buffer   strings.Builder
+	lines    int
+	depth    int
+	inString bool
+	escaped  bool
+}
+
+func (a *jsonStreamAssembler) push(line string) []string {
+	var records []string
+	if a.depth > 0 && (strings.HasPrefix(line, "{") || a.lines >= recordMaxLines) {
+		records = a.flush()
+	}
+	if a.depth > 0 {
+		a.buffer.WriteByte('\n')
+		a.lines++
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if a.depth == 0 {
+			// resynchronise on next object
+			if c != '{' {
+				continue
+			}
+			a.lines = 1
+		}
+		a.buffer.WriteByte(c)
+
+		switch {
+		case a.inString:
+			switch {
+			case a.escaped:
+				a.escaped = false
+			case c == '\\':
+				a.escaped = true
+			case c == '"':
+				a.inString = false
+			}
+		case c == '"':
+			a.inString = true
+		case c == '{' || c == '[':
+			a.depth++
+		case c == '}' || c == ']':
+			a.depth--
+			if a.depth == 0 {
+				records = append(records, a.buffer.String())
+				a.buffer.Reset()
+			}
+		}
+	}
+
+	return records
+}
+
+func (a *jsonStreamAssembler) flush() []string {
+	if a.buffer.Len() == 0 {
+		return nil
+	}
+	record := a.buffer.String()
+	a.buffer.Reset()
+	a.depth, a.lines, a.inString, a.escaped = 0, 0, false, false
+	return []string{record}
+}
+
+// assembleLines read lines of file, and handle records built by assembler.
+// Pending record is flushed after recordFlushDelay without new line.
+func (watcher *WatcherProcess) assembleLines(fileWatcher *currentWatching, assembler recordAssembler) {
+	timer := time.NewTimer(recordFlushDelay)
+	timer.Stop()
+	defer timer.Stop()
+
+	lastLineTime := time.Now()
+	handleRecords := func(records []string) {
+		for _, record := range records {
+			go watcher.processLine(fileWatcher, &tail.Line{Text: record, Time: lastLineTime})
+		}
+	}
+
+	for {
+		select {
+		case line, ok := <-fileWatcher.tail.Lines:
+			if !ok {
+				handleRecords(assembler.flush())
+				return
+			}
+			lastLineTime = line.Time
+			handleRecords(assembler.push(line.Text))
+			timer.Reset(recordFlushDelay)
+		case <-timer.C:
+			handleRecords(assembler.flush())
+		}
+	}
+}
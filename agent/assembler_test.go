@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func assembleTestRecords(assembler recordAssembler, lines []string) []string {
+	var records []string
+	for _, line := range lines {
+		records = append(records, assembler.push(line)...)
+	}
+	return append(records, assembler.flush()...)
+}
+
+func TestJSONStreamAssembler(t *testing.T) {
+	tests := map[string]struct {
+		lines   []string
+		records []string
+	}{
+		"one object per line": {
+			lines:   []string{`{"a": 1}`, `{"b": 2}`},
+			records: []string{`{"a": 1}`, `{"b": 2}`},
+		},
+		"object split across lines": {
+			lines:   []string{`{`, `  "a": 1,`, `  "b": {"c": [1, 2]}`, `}`},
+			records: []string{"{\n  \"a\": 1,\n  \"b\": {\"c\": [1, 2]}\n}"},
+		},
+		"several objects on one line": {
+			lines:   []string{`{"a": 1}{"b": 2} {"c": 3}`},
+			records: []string{`{"a": 1}`, `{"b": 2}`, `{"c": 3}`},
+		},
+		"object ending on the line of the next one": {
+			lines:   []string{`{"a":`, `1} {"b":`, `2}`},
+			records: []string{"{\"a\":\n1}", "{\"b\":\n2}"},
+		},
+		"top-level array": {
+			lines:   []string{`[`, `  {"a": 1},`, `  {"b": [2, 3]}`, `]`},
+			records: []string{`{"a": 1}`, `{"b": [2, 3]}`},
+		},
+		"top-level array on one line": {
+			lines:   []string{`[{"a": 1}, {"b": 2}]`},
+			records: []string{`{"a": 1}`, `{"b": 2}`},
+		},
+		"escaped quotes and braces in strings": {
+			lines:   []string{`{"msg": "say \"}\" and {", "path": "C:\\"}`, `{"b": "]"}`},
+			records: []string{`{"msg": "say \"}\" and {", "path": "C:\\"}`, `{"b": "]"}`},
+		},
+		"leading garbage": {
+			lines:   []string{`2024-10-10 13:55:36 garbage {"a": 1}`, `trailing text`, `{"b": 2} more garbage`},
+			records: []string{`{"a": 1}`, `{"b": 2}`},
+		},
+		"corrupted object": {
+			lines:   []string{`{"a": "unterminated`, `{"b": 2}`},
+			records: []string{`{"a": "unterminated`, `{"b": 2}`},
+		},
+		"pending object is flushed": {
+			lines:   []string{`{"a": 1}`, `{"b":`},
+			records: []string{`{"a": 1}`, `{"b":`},
+		},
+		"only garbage": {
+			lines:   []string{`garbage`, `]`},
+			records: nil,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			records := assembleTestRecords(&jsonStreamAssembler{}, test.lines)
+			if !reflect.DeepEqual(records, test.records) {
+				t.Errorf("records = %q, want %q", records, test.records)
+			}
+		})
+	}
+}
+
+func TestJSONStreamAssemblerReuse(t *testing.T) {
+	assembler := &jsonStreamAssembler{}
+	assembleTestRecords(assembler, []string{`{"a": "unterminated \`})
+
+	// state of a flushed object must not leak into the next one
+	records := assembleTestRecords(assembler, []string{`{"b": "}"}`})
+	if expected := []string{`{"b": "}"}`}; !reflect.DeepEqual(records, expected) {
+		t.Errorf("records = %q, want %q", records, expected)
+	}
+}
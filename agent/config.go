@@ -3,6 +3,10 @@ package agent
 import (
 	"fmt"
 	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/creasty/defaults"
 
@@ -10,16 +14,229 @@ import (
 )
 
 type ParserConfigStruct struct {
-	Name          string            `yaml:"name" validate:"required,simple_name"`
-	Mode          string            `yaml:"mode" validate:"required,oneof=json regex"`
-	RegexPattern  string            `yaml:"regex_pattern" validate:"required_if=Mode regex"`
-	JSONFields    map[string]string `yaml:"json_fields" validate:"required_if=Mode json,dive,required"`
-	FilesIncluded []string          `yaml:"files_included" validate:"required,gte=1,dive,required"`
-	FilesExcluded []string          `yaml:"files_excluded" validate:"dive,required"`
-	DateExtract   struct {
-		Field  string `yaml:"field"`
-		Format string `yaml:"format"`
-	} `yaml:"date_extract"`
+	Name                string                     `yaml:"name" validate:"required,simple_name"`
+	Preset              string                     `yaml:"preset"`
+	Mode                string                     `yaml:"mode" validate:"required,oneof=json json_stream xml regex mysql_slow postgresql auditd"` //nolint:lll
+	RegexPattern        string                     `yaml:"regex_pattern" validate:"required_if=Mode regex"`
+	JSONFields          map[string]string          `yaml:"json_fields" validate:"required_if=Mode json JSONCaptureAll false,required_if=Mode json_stream JSONCaptureAll false,dive,required"` //nolint:lll
+	XMLElement          string                     `yaml:"xml_element" validate:"required_if=Mode xml"`
+	XMLFields           map[string]string          `yaml:"xml_fields" validate:"dive,required"`
+	JSONCaptureAll      bool                       `yaml:"json_capture_all" default:"false"`
+	JSONCaptureMaxDepth int                        `yaml:"json_capture_max_depth" validate:"gte=1" default:"5"`
+	JSONCaptureMaxKeys  int                        `yaml:"json_capture_max_keys" validate:"gte=1" default:"200"`
+	OnParseError        string                     `yaml:"on_parse_error" validate:"required,oneof=drop keep_raw keep_with_field" default:"drop"` //nolint:lll
+	FilesIncluded       []string                   `yaml:"files_included" validate:"required,gte=1,dive,required"`
+	FilesExcluded       []string                   `yaml:"files_excluded" validate:"dive,required"`
+	DateExtract         DateExtractConfigStruct    `yaml:"date_extract"`
+	Severity            SeverityConfigStruct       `yaml:"severity"`
+	DropWhen            []TriggerValueConfigStruct `yaml:"drop_when" validate:"dive"`
+	Sampling            struct {
+		Rate  int    `yaml:"rate" validate:"gte=0"`
+		Field string `yaml:"field"`
+	} `yaml:"sampling"`
+	SubParsers []*SubParserConfigStruct `yaml:"sub_parsers" validate:"dive"`
+	Processors []*ProcessorConfigStruct `yaml:"processors" validate:"dive"`
+
+	jsonSelectors map[string]*core.JSONPath
+	xmlSelectors  map[string]*xmlSelector
+	dropWhen      triggerAllCondition
+	sampleCounter atomic.Uint64
+}
+
+func (s *ParserConfigStruct) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	_ = defaults.Set(s)
+	type plain ParserConfigStruct
+	if err := unmarshal((*plain)(s)); err != nil {
+		return err
+	}
+	if s.Preset != "" {
+		return s.applyPreset()
+	}
+	return nil
+}
+
+func (s *ParserConfigStruct) compile() error {
+	s.jsonSelectors = make(map[string]*core.JSONPath, len(s.JSONFields))
+	for internalFieldName, jsonField := range s.JSONFields {
+		selector, err := core.CompileJSONPath(jsonField)
+		if err != nil {
+			return fmt.Errorf("jsonFields.%s %w", internalFieldName, err)
+		}
+		s.jsonSelectors[internalFieldName] = selector
+	}
+	s.xmlSelectors = make(map[string]*xmlSelector, len(s.XMLFields))
+	for internalFieldName, xmlField := range s.XMLFields {
+		selector, err := compileXMLSelector(xmlField)
+		if err != nil {
+			return fmt.Errorf("xmlFields.%s %w", internalFieldName, err)
+		}
+		s.xmlSelectors[internalFieldName] = selector
+	}
+	if err := s.DateExtract.compile(); err != nil {
+		return fmt.Errorf("dateExtract.%w", err)
+	}
+	s.Severity.compile()
+	var err error
+	if s.dropWhen, err = compileTriggerConditions(s.DropWhen); err != nil {
+		return fmt.Errorf("dropWhen%w", err)
+	}
+	for i, subParser := range s.SubParsers {
+		if err := subParser.compile(); err != nil {
+			return fmt.Errorf("subParsers[%d].%w", i, err)
+		}
+	}
+	for i, processor := range s.Processors {
+		if err := processor.compile(); err != nil {
+			return fmt.Errorf("processors[%d] %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *ParserConfigStruct) jsonFlattenLimits() core.JSONFlattenLimits {
+	return core.JSONFlattenLimits{
+		MaxDepth: s.JSONCaptureMaxDepth,
+		MaxKeys:  s.JSONCaptureMaxKeys,
+	}
+}
+
+type SubParserConfigStruct struct {
+	Field             string            `yaml:"field" validate:"required"`
+	Mode              string            `yaml:"mode" validate:"required,oneof=json logfmt kv regex"`
+	Prefix            string            `yaml:"prefix"`
+	RegexPattern      string            `yaml:"regex_pattern" validate:"required_if=Mode regex"`
+	JSONFields        map[string]string `yaml:"json_fields" validate:"dive,required"`
+	PairSeparator     string            `yaml:"pair_separator" validate:"required_if=Mode kv" default:" "`
+	KeyValueSeparator string            `yaml:"key_value_separator" validate:"required_if=Mode kv" default:"="`
+
+	regex         *regexp.Regexp
+	jsonSelectors map[string]*core.JSONPath
+}
+
+func (s *SubParserConfigStruct) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	_ = defaults.Set(s)
+	type plain SubParserConfigStruct
+	if err := unmarshal((*plain)(s)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *SubParserConfigStruct) compile() error {
+	if s.Mode == subParserModeRegex {
+		regex, err := regexp.Compile(s.RegexPattern)
+		if err != nil {
+			return fmt.Errorf("regexPattern is invalid: %w", err)
+		}
+		s.regex = regex
+	}
+
+	s.jsonSelectors = make(map[string]*core.JSONPath, len(s.JSONFields))
+	for internalFieldName, jsonField := range s.JSONFields {
+		selector, err := core.CompileJSONPath(jsonField)
+		if err != nil {
+			return fmt.Errorf("jsonFields.%s %w", internalFieldName, err)
+		}
+		s.jsonSelectors[internalFieldName] = selector
+	}
+
+	return nil
+}
+
+type DateExtractConfigStruct struct {
+	Field    string   `yaml:"field"`
+	Format   string   `yaml:"format"`
+	Formats  []string `yaml:"formats" validate:"dive,required"`
+	Timezone string   `yaml:"timezone"`
+	OnError  string   `yaml:"on_error" validate:"required,oneof=drop capture_time" default:"drop"`
+
+	parser *core.DateParser
+}
+
+func (s *DateExtractConfigStruct) compile() error {
+	location, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return fmt.Errorf("timezone %w", err)
+	}
+
+	formats := s.Formats
+	if s.Format != "" {
+		formats = append([]string{s.Format}, formats...)
+	}
+	if s.Field != "" && len(formats) == 0 {
+		return fmt.Errorf("formats must not be empty when field is set")
+	}
+	if s.parser, err = core.NewDateParser(formats, location); err != nil {
+		return fmt.Errorf("formats %w", err)
+	}
+
+	return nil
+}
+
+type SeverityConfigStruct struct {
+	Field   string            `yaml:"field"`
+	Mapping map[string]string `yaml:"mapping" validate:"dive,keys,required,endkeys,oneof=trace debug info notice warning error critical alert emergency"` //nolint:lll
+
+	mapping map[string]string
+}
+
+func (s *SeverityConfigStruct) compile() {
+	s.mapping = make(map[string]string, len(s.Mapping))
+	for value, severity := range s.Mapping {
+		s.mapping[strings.ToLower(value)] = severity
+	}
+}
+
+type ProcessorConfigStruct struct {
+	Type      string   `yaml:"type" validate:"required,oneof=rename drop lowercase uppercase add extract split concat geoip user_agent lookup exception"`                                           //nolint:lll
+	Field     string   `yaml:"field" validate:"required_if=Type rename,required_if=Type extract,required_if=Type split,required_if=Type geoip,required_if=Type user_agent,required_if=Type lookup"` //nolint:lll
+	Fields    []string `yaml:"fields" validate:"dive,required"`
+	Target    string   `yaml:"target" validate:"required_if=Type rename,required_if=Type add,required_if=Type concat"`
+	Targets   []string `yaml:"targets" validate:"dive,required"`
+	Value     string   `yaml:"value"`
+	Pattern   string   `yaml:"pattern" validate:"required_if=Type extract"`
+	Separator string   `yaml:"separator" validate:"required_if=Type split"`
+	Files     []string `yaml:"files" validate:"required_if=Type geoip,dive,required"`
+	CacheSize int      `yaml:"cache_size" validate:"gte=0" default:"1000"`
+	File      string   `yaml:"file" validate:"required_if=Type lookup"`
+	Format    string   `yaml:"format" validate:"omitempty,oneof=csv yaml"`
+	Key       string   `yaml:"key" validate:"required_if=Type lookup"`
+	Columns   []string `yaml:"columns" validate:"dive,required"`
+	OnError   string   `yaml:"on_error" validate:"required,oneof=ignore stop drop" default:"ignore"`
+
+	processor processor
+}
+
+func (s *ProcessorConfigStruct) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	_ = defaults.Set(s)
+	type plain ProcessorConfigStruct
+	if err := unmarshal((*plain)(s)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *ProcessorConfigStruct) compile() error {
+	var err error
+	if s.processor, err = compileProcessor(s); err != nil {
+		return err
+	}
+	return nil
+}
+
+type RedactionPatternConfigStruct struct {
+	Name    string `yaml:"name" validate:"required,slug"`
+	Pattern string `yaml:"pattern" validate:"required"`
+}
+
+type RedactionConfigStruct struct {
+	Detectors []string                       `yaml:"detectors" validate:"unique,dive,oneof=email pan ipv4 ipv6 jwt bearer"`
+	Custom    []RedactionPatternConfigStruct `yaml:"custom" validate:"dive"`
+	Mode      string                         `yaml:"mode" validate:"required,oneof=mask drop hash" default:"mask"`
+	HashKey   string                         `yaml:"hash_key" validate:"required_if=Mode hash"`
+
+	redactor *redactor
 }
 
 type RecipientConfigStruct struct {
@@ -27,30 +244,121 @@ type RecipientConfigStruct struct {
 	Recipient string `yaml:"recipient" validate:"required"`
 }
 
+// TriggerValue is the value of a trigger condition, either a scalar or a list (e.g. for "in" operator).
+type TriggerValue []string
+
+func (v *TriggerValue) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var list []string
+	if err := unmarshal(&list); err == nil {
+		*v = list
+		return nil
+	}
+	var value string
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	*v = TriggerValue{value}
+	return nil
+}
+
+// String returns values separated by commas.
+func (v TriggerValue) String() string {
+	return strings.Join(v, triggerListSeparator)
+}
+
+// TriggerValueConfigStruct is a condition of a trigger, either a field condition (field, operator and value)
+// or a group of conditions : "all" (and), "any" (or) or "not". Groups can be nested.
 type TriggerValueConfigStruct struct {
-	Field    string `yaml:"field" validate:"required"`
-	Operator string `yaml:"operator" validate:"required,oneof=regex is is_not contains not_contains start_with not_start_with match_regex"`
-	Value    string `yaml:"value" validate:"required"`
+	Field         string       `yaml:"field"`
+	Operator      string       `yaml:"operator" validate:"omitempty,oneof=regex is is_not contains not_contains start_with not_start_with match_regex severity_gte severity_lte gt gte lt lte between eq_num in not_in exists not_exists cidr glob"` //nolint:lll
+	Value         TriggerValue `yaml:"value"`
+	CaseSensitive bool         `yaml:"case_sensitive" default:"false"`
+
+	All []TriggerValueConfigStruct `yaml:"all" validate:"dive"`
+	Any []TriggerValueConfigStruct `yaml:"any" validate:"dive"`
+	Not *TriggerValueConfigStruct  `yaml:"not"`
+}
+
+func (s *TriggerValueConfigStruct) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	_ = defaults.Set(s)
+	type plain TriggerValueConfigStruct
+	if err := unmarshal((*plain)(s)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// TriggerThresholdConfigStruct delay alerts of a trigger until "count" entries match within "window",
+// counted separately for each combination of values of "group_by" fields.
+type TriggerThresholdConfigStruct struct {
+	Count   int           `yaml:"count" validate:"required,gte=1"`
+	Window  time.Duration `yaml:"window" validate:"required,gt=0"`
+	GroupBy []string      `yaml:"group_by" validate:"dive,required"`
+	Samples int           `yaml:"samples" validate:"gte=0" default:"3"`
+}
+
+func (s *TriggerThresholdConfigStruct) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	_ = defaults.Set(s)
+	type plain TriggerThresholdConfigStruct
+	if err := unmarshal((*plain)(s)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// TriggerAbsenceConfigStruct alert when no entry match the trigger within "interval", for each combination
+// of values of "group_by" fields, then when entries resume.
+type TriggerAbsenceConfigStruct struct {
+	Interval time.Duration `yaml:"interval" validate:"required,gt=0"`
+	GroupBy  []string      `yaml:"group_by" validate:"dive,required"`
 }
 
 type TriggerConfigStruct struct {
-	Name   string                     `yaml:"name" validate:"required,simple_name"`
-	Values []TriggerValueConfigStruct `yaml:"values" validate:"required_if=Kind Values,dive,required"`
+	Name       string                        `yaml:"name" validate:"required,simple_name"`
+	Values     []TriggerValueConfigStruct    `yaml:"values" validate:"required_without=Expression,dive,required"`
+	Expression string                        `yaml:"expression"`
+	Threshold  *TriggerThresholdConfigStruct `yaml:"threshold"`
+	Absence    *TriggerAbsenceConfigStruct   `yaml:"absence"`
+
+	condition  triggerAllCondition
+	expression *core.Expression
+}
+
+func (s *TriggerConfigStruct) compile() error {
+	if s.Threshold != nil && s.Absence != nil {
+		return fmt.Errorf("absence can not be combined with threshold")
+	}
+
+	var err error
+	if s.condition, err = compileTriggerConditions(s.Values); err != nil {
+		return fmt.Errorf("values%w", err)
+	}
+
+	if s.Expression != "" {
+		if s.expression, err = core.CompileExpression(s.Expression); err != nil {
+			return fmt.Errorf("expression is invalid at %w", err)
+		}
+	}
+	return nil
 }
 
 type AlertConfigStruct struct {
 	Frequency  int64                   `yaml:"frequency" validate:"required,gt=0" default:"5"`
 	Recipients []RecipientConfigStruct `yaml:"recipients" validate:"dive"`
 	Triggers   []TriggerConfigStruct   `yaml:"triggers" validate:"dive"`
+
+	matcher *triggerMatcher
 }
 
 type AgentConfig struct {
-	Debug       bool                  `yaml:"debug" default:"false"`
-	Application string                `yaml:"application" validate:"required,simple_name"`
-	Server      string                `yaml:"server"`
-	Parsers     []*ParserConfigStruct `yaml:"parsers" validate:"required,gte=1,unique=Name,dive"`
-	Alerts      AlertConfigStruct     `yaml:"alerts" validate:""`
-	SMTP        core.SMTPConfig       `yaml:"smtp"`
+	Debug       bool                     `yaml:"debug" default:"false"`
+	Application string                   `yaml:"application" validate:"required,simple_name"`
+	Server      string                   `yaml:"server"`
+	Parsers     []*ParserConfigStruct    `yaml:"parsers" validate:"required,gte=1,unique=Name,dive"`
+	Processors  []*ProcessorConfigStruct `yaml:"processors" validate:"dive"`
+	Redaction   RedactionConfigStruct    `yaml:"redaction"`
+	Alerts      AlertConfigStruct        `yaml:"alerts" validate:""`
+	SMTP        core.SMTPConfig          `yaml:"smtp"`
 }
 
 func (s *AgentConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -62,6 +370,34 @@ func (s *AgentConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	return nil
 }
 
+// Compile prepare runtime structures of the config, it is called once config is loaded and validated.
+func (s *AgentConfig) Compile() error {
+	for i, parser := range s.Parsers {
+		if err := parser.compile(); err != nil {
+			return fmt.Errorf("parsers[%d].%w", i, err)
+		}
+	}
+	for i, processor := range s.Processors {
+		if err := processor.compile(); err != nil {
+			return fmt.Errorf("processors[%d] %w", i, err)
+		}
+	}
+
+	for i := range s.Alerts.Triggers {
+		if err := s.Alerts.Triggers[i].compile(); err != nil {
+			return fmt.Errorf("alerts.triggers[%d].%w", i, err)
+		}
+	}
+	s.Alerts.matcher = newTriggerMatcher(s.Alerts.Triggers)
+
+	var err error
+	if s.Redaction.redactor, err = compileRedactor(&s.Redaction); err != nil {
+		return fmt.Errorf("redaction.%w", err)
+	}
+
+	return nil
+}
+
 func CheckConfig(configFile string) {
 	if err := core.ReadConfig(configFile, AppConfig); err != nil {
 		fmt.Printf("Invalid config file : %s\n", err)
@@ -0,0 +1,114 @@
+package agent
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gobana-agent/core"
+)
+
+// readTestConfig load a config file content through the same path as the agent : decode, validate and compile.
+func readTestConfig(t testing.TB, content string) (*AgentConfig, error) {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(filename, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config := &AgentConfig{}
+	if err := core.ReadConfig(filename, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func mustReadTestConfig(t testing.TB, content string) *AgentConfig {
+	t.Helper()
+	config, err := readTestConfig(t, content)
+	if err != nil {
+		t.Fatalf("unexpected config error : %s", err)
+	}
+	return config
+}
+
+const dropWhenTestConfig = `
+application: test
+smtp:
+  from_email: "gobana@example.com"
+parsers:
+  - name: app
+    mode: json
+    json_capture_all: true
+    files_included: ["/var/log/app.log"]
+    drop_when:
+%s
+`
+
+func TestDropWhenInvalidConfig(t *testing.T) {
+	tests := map[string]struct {
+		dropWhen string
+		error    string
+	}{
+		"invalid regex": {
+			dropWhen: `      - {field: path, operator: match_regex, value: "(healthz"}`,
+			error:    "parsers[0].dropWhen[0].value",
+		},
+		"invalid number": {
+			dropWhen: `      - {field: duration, operator: gt, value: "fast"}`,
+			error:    "parsers[0].dropWhen[0].value",
+		},
+		"invalid nested regex": {
+			dropWhen: `      - any: [{field: path, operator: is, value: "/"}, {field: path, operator: regex, value: "[a-"}]`,
+			error:    "parsers[0].dropWhen[0].any[1].value",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := readTestConfig(t, strings.Replace(dropWhenTestConfig, "%s", test.dropWhen, 1))
+			if err == nil {
+				t.Fatalf("config must be invalid")
+			}
+			if !strings.Contains(err.Error(), test.error) {
+				t.Errorf("error %q must contain %q", err, test.error)
+			}
+		})
+	}
+}
+
+func TestDropWhen(t *testing.T) {
+	config := mustReadTestConfig(t, strings.Replace(dropWhenTestConfig, "%s", `      - {field: path, operator: match_regex, value: "^/health"}
+      - {field: status, operator: lt, value: "400"}`, 1))
+	parser := config.Parsers[0]
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	tests := map[string]struct {
+		fields map[string]string
+		kept   bool
+	}{
+		"all conditions match":   {fields: map[string]string{"path": "/healthz", "status": "200"}, kept: false},
+		"one condition mismatch": {fields: map[string]string{"path": "/healthz", "status": "500"}, kept: true},
+		"other path":             {fields: map[string]string{"path": "/login", "status": "200"}, kept: true},
+		"missing field":          {fields: map[string]string{"status": "200"}, kept: true},
+		"no fields":              {fields: map[string]string{}, kept: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			entry := &core.Entry{Fields: test.fields}
+			if kept := filterEntry(parser, entry); kept != test.kept {
+				t.Errorf("filterEntry() = %t, want %t", kept, test.kept)
+			}
+		})
+	}
+
+	if logs.Len() > 0 {
+		t.Errorf("drop_when must not log, got %q", logs.String())
+	}
+}
@@ -0,0 +1,39 @@
+package agent
+
+import (
+	"hash/fnv"
+
+	"gobana-agent/core"
+)
+
+// filterEntry apply parser "drop_when" conditions and sampling.
+// It returns false if the entry must be discarded.
+func filterEntry(parser *ParserConfigStruct, entry *core.Entry) bool {
+	if len(parser.dropWhen) > 0 && parser.dropWhen.match(entry) {
+		return false
+	}
+
+	return sampleEntry(parser, entry)
+}
+
+// sampleEntry keep 1 entry in N. Sampling is hash-based when a field is configured :
+// all entries sharing the same field value are either kept or discarded.
+func sampleEntry(parser *ParserConfigStruct, entry *core.Entry) bool {
+	rate := uint64(parser.Sampling.Rate)
+	if rate <= 1 {
+		return true
+	}
+
+	if parser.Sampling.Field != "" {
+		value, ok := entry.Fields[parser.Sampling.Field]
+		if !ok {
+			// without value, entry can't be sampled
+			return true
+		}
+		hash := fnv.New64a()
+		_, _ = hash.Write([]byte(value))
+		return hash.Sum64()%rate == 0
+	}
+
+	return (parser.sampleCounter.Add(1)-1)%rate == 0
+}
@@ -0,0 +1,219 @@
+package agent
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"gobana-agent/core"
+)
+
+const (
+	auditdRecordTypeEOE = "EOE"
+
+	// separator of enriched fields (log_format = ENRICHED)
+	auditdEnrichedSeparator = "\x1d"
+)
+
+var (
+	// "type=SYSCALL msg=audit(1364481363.243:24287): ...", optionally prefixed (e.g. by syslog or "node=host")
+	auditdLineRegex = regexp.MustCompile(`(?:^|\s)type=(\S+) msg=audit\((\d+(?:\.\d+)?):(\d+)\)\s*:\s?(.*)$`)
+
+	// fields logged hex-encoded (without quotes) when they contain special characters
+	auditdEncodedFields = []string{"name", "exe", "comm", "ocomm", "cwd", "proctitle", "key", "path", "cmd", "acct", "data", "dir", "file"}
+)
+
+type auditdRecord struct {
+	recordType string
+	timestamp  string
+	serial     string
+	values     map[string]string
+	keys       []string
+}
+
+func parseAuditdLine(line string) (*auditdRecord, bool) {
+	matches := auditdLineRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return nil, false
+	}
+
+	record := &auditdRecord{recordType: matches[1], timestamp: matches[2], serial: matches[3], values: map[string]string{}}
+	record.parseValues(strings.ReplaceAll(matches[4], auditdEnrichedSeparator, " "))
+	return record, true
+}
+
+// parseValues read "key=value" pairs. Values are either quoted, single-quoted (nested pairs, e.g. msg='op=login ...')
+// or unquoted, unquoted values of encoded fields are hex-decoded.
+func (record *auditdRecord) parseValues(text string) {
+	for _, token := range splitAuditdTokens(text) {
+		key, value, ok := strings.Cut(token, "=")
+		if !ok || key == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") && len(value) >= 2:
+			record.parseValues(value[1 : len(value)-1])
+			continue
+		case strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) && len(value) >= 2:
+			value = value[1 : len(value)-1]
+		case record.isEncodedField(key):
+			value = decodeAuditdHex(value)
+		}
+
+		// enriched fields are upper case, e.g. "AUID" is the name of user "auid"
+		if strings.ToUpper(key) == key && strings.ToLower(key) != key {
+			key = strings.ToLower(key) + "_name"
+		}
+		if _, exists := record.values[key]; !exists {
+			record.keys = append(record.keys, key)
+		}
+		record.values[key] = value
+	}
+}
+
+func (record *auditdRecord) isEncodedField(key string) bool {
+	if record.recordType == "EXECVE" && len(key) > 1 && key[0] == 'a' && strings.Trim(key[1:], "0123456789") == "" {
+		return true
+	}
+	return core.SliceContains(auditdEncodedFields, key)
+}
+
+// splitAuditdTokens split text on spaces, except inside quotes.
+func splitAuditdTokens(text string) []string {
+	var tokens []string
+	var quote rune
+	start := -1
+	for i, c := range text {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			if start < 0 {
+				start = i
+			}
+		case unicode.IsSpace(c):
+			if start >= 0 {
+				tokens = append(tokens, text[start:i])
+				start = -1
+			}
+		default:
+			if start < 0 {
+				start = i
+			}
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, text[start:])
+	}
+	return tokens
+}
+
+// decodeAuditdHex decode hex-encoded values, NUL characters (e.g. proctitle arguments separators) become spaces.
+func decodeAuditdHex(value string) string {
+	if len(value) < 2 || len(value)%2 != 0 {
+		return value
+	}
+	decoded, err := hex.DecodeString(value)
+	if err != nil {
+		return value
+	}
+	return strings.TrimSpace(strings.ReplaceAll(string(decoded), "\x00", " "))
+}
+
+// auditdStartsRecord returns true if line belongs to another event than block.
+func auditdStartsRecord(block []string, line string) bool {
+	record, ok := parseAuditdLine(line)
+	if !ok {
+		return true
+	}
+	first, ok := parseAuditdLine(block[0])
+	return !ok || first.serial != record.serial
+}
+
+// auditdEndsRecord returns true on the end of event record, and on lines which are not audit records.
+func auditdEndsRecord(line string) bool {
+	record, ok := parseAuditdLine(line)
+	return !ok || record.recordType == auditdRecordTypeEOE
+}
+
+// parseAuditdRecord combine records of an audit event (SYSCALL, PATH, EXECVE...) in one entry :
+//   - fields of the first record are captured as is, with "type" containing its record type
+//   - "cwd", "proctitle", "command" (EXECVE arguments) and "paths" (names of PATH records) summarise other records
+//   - other fields of other records are captured as "<record type>.<key>", e.g. "path.0.name" or "sockaddr.saddr"
+func parseAuditdRecord(fields map[string]string, event string) error {
+	var records []*auditdRecord
+	for _, line := range strings.Split(event, "\n") {
+		record, ok := parseAuditdLine(line)
+		if !ok {
+			return fmt.Errorf("line is not an audit record (%s)", line)
+		}
+		if record.recordType != auditdRecordTypeEOE {
+			records = append(records, record)
+		}
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("event without record")
+	}
+
+	primary := records[0]
+	fields["date"] = primary.timestamp
+	fields["serial"] = primary.serial
+	fields["type"] = primary.recordType
+	for _, key := range primary.keys {
+		fields[key] = primary.values[key]
+	}
+
+	recordTypes := make([]string, 0, len(records))
+	var paths []string
+	for i, record := range records {
+		recordTypes = append(recordTypes, record.recordType)
+		if i == 0 {
+			continue
+		}
+
+		prefix := strings.ToLower(record.recordType)
+		switch record.recordType {
+		case "CWD":
+			fields["cwd"] = record.values["cwd"]
+			continue
+		case "PROCTITLE":
+			fields["proctitle"] = record.values["proctitle"]
+			continue
+		case "EXECVE":
+			fields["command"] = auditdCommand(record)
+		case "PATH":
+			prefix = "path." + record.values["item"]
+			if name, ok := record.values["name"]; ok && name != "(null)" {
+				paths = append(paths, name)
+			}
+		}
+		for _, key := range record.keys {
+			fields[prefix+"."+key] = record.values[key]
+		}
+	}
+	fields["record_types"] = strings.Join(recordTypes, ",")
+	if len(paths) > 0 {
+		fields["paths"] = strings.Join(paths, ",")
+	}
+
+	return nil
+}
+
+// auditdCommand join EXECVE arguments (a0, a1...) in a command line.
+func auditdCommand(record *auditdRecord) string {
+	var args []string
+	for i := 0; ; i++ {
+		arg, ok := record.values[fmt.Sprintf("a%d", i)]
+		if !ok {
+			break
+		}
+		args = append(args, arg)
+	}
+	return strings.Join(args, " ")
+}
@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAuditdRecord(t *testing.T) {
+	tests := map[string]struct {
+		lines    []string
+		fields   map[string]string
+		hasError bool
+	}{
+		"single record": {
+			lines: []string{`type=USER_LOGIN msg=audit(1728568536.243:101): pid=42 uid=0 res=failed`},
+			fields: map[string]string{
+				"date": "1728568536.243", "serial": "101", "type": "USER_LOGIN", "pid": "42", "uid": "0", "res": "failed",
+				"record_types": "USER_LOGIN",
+			},
+		},
+		"nested message": {
+			lines: []string{
+				`type=USER_AUTH msg=audit(1728568536.243:102): pid=42 msg='op=PAM:authentication acct="bob" exe="/usr/sbin/sshd" res=failed'`,
+			},
+			fields: map[string]string{
+				"date": "1728568536.243", "serial": "102", "type": "USER_AUTH", "pid": "42", "op": "PAM:authentication",
+				"acct": "bob", "exe": "/usr/sbin/sshd", "res": "failed", "record_types": "USER_AUTH",
+			},
+		},
+		"enriched fields": {
+			lines: []string{"type=SYSCALL msg=audit(1728568536:103): auid=1000 uid=0 comm=\"id\"\x1dAUID=\"bob\" UID=\"root\""},
+			fields: map[string]string{
+				"date": "1728568536", "serial": "103", "type": "SYSCALL", "auid": "1000", "uid": "0", "comm": "id",
+				"auid_name": "bob", "uid_name": "root", "record_types": "SYSCALL",
+			},
+		},
+		"prefixed line": {
+			lines: []string{`Oct 10 13:55:36 web1 audispd: node=web1 type=USER_END msg=audit(1728568536.243:104): pid=42`},
+			fields: map[string]string{
+				"date": "1728568536.243", "serial": "104", "type": "USER_END", "pid": "42", "record_types": "USER_END",
+			},
+		},
+		"event of several records": {
+			lines: []string{
+				`type=SYSCALL msg=audit(1728568536.243:105): syscall=59 success=yes exe="/usr/bin/cat" key="files"`,
+				`type=EXECVE msg=audit(1728568536.243:105): argc=2 a0="cat" a1=6D792066696C65`,
+				`type=CWD msg=audit(1728568536.243:105): cwd="/tmp"`,
+				`type=PATH msg=audit(1728568536.243:105): item=0 name="/usr/bin/cat" mode=0100755`,
+				`type=PATH msg=audit(1728568536.243:105): item=1 name=6D792066696C65 mode=0100644`,
+				`type=PATH msg=audit(1728568536.243:105): item=2 name=(null)`,
+				`type=PROCTITLE msg=audit(1728568536.243:105): proctitle=636174002F746D702F6D792066696C65`,
+				`type=EOE msg=audit(1728568536.243:105):`,
+			},
+			fields: map[string]string{
+				"date": "1728568536.243", "serial": "105", "type": "SYSCALL", "syscall": "59", "success": "yes",
+				"exe": "/usr/bin/cat", "key": "files",
+				"command": "cat my file", "execve.argc": "2", "execve.a0": "cat", "execve.a1": "my file",
+				"cwd":         "/tmp",
+				"path.0.item": "0", "path.0.name": "/usr/bin/cat", "path.0.mode": "0100755",
+				"path.1.item": "1", "path.1.name": "my file", "path.1.mode": "0100644",
+				"path.2.item": "2", "path.2.name": "(null)",
+				"paths":        "/usr/bin/cat,my file",
+				"proctitle":    "cat /tmp/my file",
+				"record_types": "SYSCALL,EXECVE,CWD,PATH,PATH,PATH,PROCTITLE",
+			},
+		},
+		"not an audit record": {lines: []string{`Oct 10 13:55:36 web1 sshd[42]: Accepted password for bob`}, hasError: true},
+		"end of event only":   {lines: []string{`type=EOE msg=audit(1728568536.243:106):`}, hasError: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			fields := map[string]string{}
+			err := parseAuditdRecord(fields, strings.Join(test.lines, "\n"))
+			if (err != nil) != test.hasError {
+				t.Fatalf("parseAuditdRecord() error = %v, want error %t", err, test.hasError)
+			}
+			if !test.hasError && !reflect.DeepEqual(fields, test.fields) {
+				t.Errorf("fields = %v, want %v", fields, test.fields)
+			}
+		})
+	}
+}
+
+func TestAuditdRecordBoundaries(t *testing.T) {
+	syscall := `type=SYSCALL msg=audit(1728568536.243:105): syscall=59`
+	tests := map[string]struct {
+		line   string
+		starts bool
+		ends   bool
+	}{
+		"same event":        {line: `type=CWD msg=audit(1728568536.243:105): cwd="/tmp"`, starts: false, ends: false},
+		"end of same event": {line: `type=EOE msg=audit(1728568536.243:105):`, starts: false, ends: true},
+		"other event":       {line: `type=SYSCALL msg=audit(1728568537.001:106): syscall=2`, starts: true, ends: false},
+		"not a record":      {line: `garbage`, starts: true, ends: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if starts := auditdStartsRecord([]string{syscall}, test.line); starts != test.starts {
+				t.Errorf("auditdStartsRecord(%q) = %t, want %t", test.line, starts, test.starts)
+			}
+			if ends := auditdEndsRecord(test.line); ends != test.ends {
+				t.Errorf("auditdEndsRecord(%q) = %t, want %t", test.line, ends, test.ends)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gobana-agent/core"
+)
+
+const (
+	mysqlSlowTimePrefix     = "# Time:"
+	mysqlSlowUserHostPrefix = "# User@Host:"
+)
+
+var (
+	// lines written by mysqld at start of slow query log
+	mysqlSlowHeaderRegex    = regexp.MustCompile(`^(?:\S+, Version: .*started with:|Tcp port: .*|Time\s+Id\s+Command\s+Argument)$`)
+	mysqlSlowUserHostRegex  = regexp.MustCompile(`^# User@Host: (\S*?)\[[^\]]*\] @ +(\S*) *\[([^\]]*)\](?:\s+Id:\s+(\d+))?`)
+	mysqlSlowMetricRegex    = regexp.MustCompile(`(\w+): (\S*)`)
+	mysqlSlowUseRegex       = regexp.MustCompile("(?i)^use\\s+`?([^`;\\s]+)`?;$")
+	mysqlSlowTimestampRegex = regexp.MustCompile(`(?i)^SET\s+timestamp\s*=\s*(\d+);$`)
+)
+
+// mysqlSlowIgnoreLine returns true for header lines of slow query log.
+func mysqlSlowIgnoreLine(line string) bool {
+	return mysqlSlowHeaderRegex.MatchString(line)
+}
+
+// mysqlSlowStartsRecord returns true if line begins a new slow query record : a "# Time:" line, or a
+// "# User@Host:" line when the time line is omitted (mysqld writes it only when time changes).
+func mysqlSlowStartsRecord(block []string, line string) bool {
+	if strings.HasPrefix(line, mysqlSlowTimePrefix) {
+		return true
+	}
+	if !strings.HasPrefix(line, mysqlSlowUserHostPrefix) {
+		return false
+	}
+	for _, blockLine := range block {
+		if !strings.HasPrefix(blockLine, mysqlSlowTimePrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseMySQLSlowRecord extract fields of a slow query record :
+//
+//	# Time: 2024-10-10T13:55:36.123456Z
+//	# User@Host: app[app] @ localhost [127.0.0.1]  Id:    12
+//	# Query_time: 2.000123  Lock_time: 0.000100 Rows_sent: 1  Rows_examined: 100000
+//	use shop;
+//	SET timestamp=1728568536;
+//	SELECT * FROM orders WHERE id = 5;
+func parseMySQLSlowRecord(fields map[string]string, record string) error {
+	var queryLines []string
+	for _, line := range strings.Split(record, "\n") {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case strings.HasPrefix(line, mysqlSlowTimePrefix):
+			fields["date"] = strings.Join(strings.Fields(strings.TrimPrefix(line, mysqlSlowTimePrefix)), " ")
+		case strings.HasPrefix(line, mysqlSlowUserHostPrefix):
+			if matches := mysqlSlowUserHostRegex.FindStringSubmatch(line); matches != nil {
+				fields["user"] = matches[1]
+				fields["host"] = matches[2]
+				fields["ip"] = matches[3]
+				if matches[4] != "" {
+					fields["thread_id"] = matches[4]
+				}
+			}
+		case strings.HasPrefix(line, "#"):
+			// metrics, e.g. "# Query_time: 2.000123  Lock_time: 0.000100" or "# Schema: shop  QC_hit: No"
+			for _, matches := range mysqlSlowMetricRegex.FindAllStringSubmatch(line, -1) {
+				name := strings.ToLower(matches[1])
+				if name == "schema" {
+					name = "database"
+				}
+				fields[name] = matches[2]
+			}
+		default:
+			trimmedLine := strings.TrimSpace(line)
+			if matches := mysqlSlowUseRegex.FindStringSubmatch(trimmedLine); matches != nil {
+				fields["database"] = matches[1]
+				continue
+			}
+			if matches := mysqlSlowTimestampRegex.FindStringSubmatch(trimmedLine); matches != nil {
+				fields["timestamp"] = matches[1]
+				continue
+			}
+			queryLines = append(queryLines, line)
+		}
+	}
+
+	if _, ok := fields["query_time"]; !ok {
+		return fmt.Errorf("record is not a slow query (Query_time not found)")
+	}
+	// without "# Time:" line, date is given by "SET timestamp"
+	if _, ok := fields["date"]; !ok && fields["timestamp"] != "" {
+		fields["date"] = fields["timestamp"]
+	}
+
+	query := strings.TrimSpace(strings.Join(queryLines, "\n"))
+	fields["query"] = query
+	fields["query_normalized"] = core.NormalizeSQL(query)
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gobana-agent/core"
+)
+
+var (
+	// line prefix "%m [%p] ", "%m [%p] %q%u@%d " or "%t [%p]: [%l-1] user=%u,db=%d,app=%a,client=%h "
+	postgresqlLineRegex = regexp.MustCompile(
+		`^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}(?:\.\d+)?(?: [A-Za-z0-9+\-]+)?) \[(\d+)\](?:: \[\d+-\d+\])?:?(?: (.*?))? ` +
+			`(DEBUG[1-5]?|INFO|NOTICE|WARNING|ERROR|LOG|FATAL|PANIC|DETAIL|HINT|QUERY|CONTEXT|LOCATION|STATEMENT):\s+(.*)$`,
+	)
+	postgresqlDurationRegex  = regexp.MustCompile(`(?s)^duration: ([\d.]+) ms(?:\s+(?:statement|(?:execute|parse|bind) [^:]*): (.*))?$`)
+	postgresqlStatementRegex = regexp.MustCompile(`(?s)^statement: (.*)$`)
+
+	// levels of lines completing the previous message of the same process
+	postgresqlSecondaryLevels = []string{"DETAIL", "HINT", "QUERY", "CONTEXT", "LOCATION", "STATEMENT"}
+)
+
+type postgresqlLine struct {
+	date    string
+	pid     string
+	prefix  string
+	level   string
+	message string
+}
+
+func parsePostgreSQLLine(line string) (*postgresqlLine, bool) {
+	matches := postgresqlLineRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return nil, false
+	}
+	return &postgresqlLine{date: matches[1], pid: matches[2], prefix: matches[3], level: matches[4], message: matches[5]}, true
+}
+
+// postgresqlStartsRecord returns true if line begins a new record. Lines without prefix continue the
+// previous message, and secondary lines (DETAIL, STATEMENT...) of the same process complete the record.
+func postgresqlStartsRecord(block []string, line string) bool {
+	parsedLine, ok := parsePostgreSQLLine(line)
+	if !ok {
+		return false
+	}
+	if !core.SliceContains(postgresqlSecondaryLevels, parsedLine.level) {
+		return true
+	}
+	first, ok := parsePostgreSQLLine(block[0])
+	return !ok || first.pid != parsedLine.pid
+}
+
+// parsePostgreSQLRecord extract fields of a PostgreSQL record :
+//
+//	2024-10-10 13:55:36.123 UTC [1234] app@shop LOG:  duration: 2001.123 ms  statement: SELECT *
+//		FROM orders WHERE id = 5;
+//	2024-10-10 13:55:36.200 UTC [1234] app@shop ERROR:  syntax error at or near "x" at character 8
+//	2024-10-10 13:55:36.200 UTC [1234] app@shop STATEMENT:  SELEC x
+func parsePostgreSQLRecord(fields map[string]string, record string) error {
+	lines := strings.Split(record, "\n")
+	first, ok := parsePostgreSQLLine(lines[0])
+	if !ok {
+		return fmt.Errorf("first line does not match postgresql log line prefix (%s)", lines[0])
+	}
+	fields["date"] = first.date
+	fields["pid"] = first.pid
+	fields["level"] = first.level
+	parsePostgreSQLPrefix(fields, first.prefix)
+
+	// group continuation lines with their message
+	sections := map[string][]string{"message": {first.message}}
+	current := "message"
+	for _, line := range lines[1:] {
+		if parsedLine, ok := parsePostgreSQLLine(line); ok {
+			current = strings.ToLower(parsedLine.level)
+			sections[current] = append(sections[current], parsedLine.message)
+			continue
+		}
+		sections[current] = append(sections[current], strings.TrimPrefix(strings.TrimRight(line, "\r"), "\t"))
+	}
+	for name, sectionLines := range sections {
+		fields[name] = strings.TrimSpace(strings.Join(sectionLines, "\n"))
+	}
+
+	// query and duration
+	query := fields["statement"]
+	if matches := postgresqlDurationRegex.FindStringSubmatch(fields["message"]); matches != nil {
+		fields["duration_ms"] = matches[1]
+		if duration, err := strconv.ParseFloat(matches[1], 64); err == nil {
+			fields["query_time"] = strconv.FormatFloat(duration/1000, 'f', -1, 64)
+		}
+		if matches[2] != "" {
+			query = matches[2]
+		}
+	} else if matches := postgresqlStatementRegex.FindStringSubmatch(fields["message"]); matches != nil {
+		query = matches[1]
+	}
+	if query != "" {
+		fields["query"] = strings.TrimSpace(query)
+		fields["query_normalized"] = core.NormalizeSQL(query)
+	}
+
+	return nil
+}
+
+// parsePostgreSQLPrefix extract user, database, application and host from the variable part of line prefix.
+func parsePostgreSQLPrefix(fields map[string]string, prefix string) {
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		return
+	}
+
+	if !strings.Contains(prefix, "=") {
+		// "%u@%d"
+		if user, database, ok := strings.Cut(prefix, "@"); ok {
+			fields["user"] = user
+			fields["database"] = database
+		}
+		return
+	}
+
+	// "user=%u,db=%d,app=%a,client=%h"
+	names := map[string]string{"user": "user", "db": "database", "app": "application", "client": "host", "host": "host"}
+	for key, value := range core.ParseKeyValues(prefix, ",", "=") {
+		if name, ok := names[key]; ok && value != "[unknown]" {
+			fields[name] = value
+		}
+	}
+}
@@ -0,0 +1,359 @@
+package agent
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var xmlSelectorStepRegex = regexp.MustCompile(`^(\*|[\w.\-:]+)(?:\[(?:@([\w.\-:]+)=['"]([^'"]*)['"]|(\d+))\])?$`)
+
+// xmlNode is an element of an xml record, names are local names (without namespace prefix).
+type xmlNode struct {
+	name     string
+	attrs    map[string]string
+	attrKeys []string
+	text     string
+	children []*xmlNode
+}
+
+// attr returns the value of an attribute.
+func (node *xmlNode) attr(name string) (string, bool) {
+	value, ok := node.attrs[name]
+	return value, ok
+}
+
+// key returns the name used to flatten node : its "Name" / "name" attribute if any, else its element name.
+func (node *xmlNode) key() string {
+	for _, attr := range []string{"Name", "name"} {
+		if value, ok := node.attrs[attr]; ok && value != "" {
+			return value
+		}
+	}
+	return node.name
+}
+
+// parseXMLRecord decode an xml record in a tree of nodes.
+// Decoder is not strict, so undeclared namespace prefixes (e.g. log4j XMLLayout) and html entities are accepted.
+func parseXMLRecord(record string) (*xmlNode, error) {
+	decoder := xml.NewDecoder(strings.NewReader(record))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	var root *xmlNode
+	var stack []*xmlNode
+	texts := map[*xmlNode]*strings.Builder{}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid xml: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name.Local, attrs: make(map[string]string, len(t.Attr))}
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+					continue
+				}
+				if _, exists := node.attrs[attr.Name.Local]; !exists {
+					node.attrKeys = append(node.attrKeys, attr.Name.Local)
+				}
+				node.attrs[attr.Name.Local] = attr.Value
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			} else if root == nil {
+				root = node
+			}
+			texts[node] = &strings.Builder{}
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				continue
+			}
+			node := stack[len(stack)-1]
+			node.text = strings.TrimSpace(texts[node].String())
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				texts[stack[len(stack)-1]].Write(t)
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("no xml element found")
+	}
+	// unclosed elements
+	for _, node := range stack {
+		node.text = strings.TrimSpace(texts[node].String())
+	}
+
+	return root, nil
+}
+
+type xmlSelectorStep struct {
+	name      string
+	attrName  string
+	attrValue string
+	index     int
+}
+
+func (step *xmlSelectorStep) match(node *xmlNode) bool {
+	if step.name != "*" && step.name != node.name {
+		return false
+	}
+	if step.attrName != "" {
+		value, ok := node.attr(step.attrName)
+		return ok && value == step.attrValue
+	}
+	return true
+}
+
+// xmlSelector select values of an xml record with an XPath-like syntax, relative to record element :
+//   - "@level" : attribute of record element
+//   - "message" : text of child element, "locationInfo/file" : text of a nested element
+//   - "locationInfo/@line" : attribute of a child element
+//   - "Data[@Name='TargetUserName']" : element with an attribute value, "Data[2]" : second element (from 1)
+//   - "EventData/*" : all elements (wildcard), values are captured as "<field>.<key>"
+//     where key is the "Name" attribute of element, or its name
+//
+// Namespace prefixes are ignored, e.g. "log4j:message" is the same as "message".
+type xmlSelector struct {
+	steps     []xmlSelectorStep
+	attribute string
+	wildcard  bool
+}
+
+type xmlMatch struct {
+	key   string
+	value string
+}
+
+func compileXMLSelector(expression string) (*xmlSelector, error) {
+	selector := &xmlSelector{}
+	expression = strings.Trim(strings.TrimSpace(expression), "/")
+	if expression == "" {
+		return nil, fmt.Errorf("selector must not be empty")
+	}
+
+	parts := strings.Split(expression, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, "@") {
+			if i != len(parts)-1 || len(part) == 1 {
+				return nil, fmt.Errorf("invalid selector \"%s\": attribute must be the last part", expression)
+			}
+			selector.attribute = stripXMLPrefix(part[1:])
+			break
+		}
+
+		matches := xmlSelectorStepRegex.FindStringSubmatch(part)
+		if matches == nil {
+			return nil, fmt.Errorf("invalid selector \"%s\": invalid part \"%s\"", expression, part)
+		}
+		step := xmlSelectorStep{name: stripXMLPrefix(matches[1]), attrName: stripXMLPrefix(matches[2]), attrValue: matches[3]}
+		if matches[4] != "" {
+			step.index, _ = strconv.Atoi(matches[4])
+			if step.index < 1 {
+				return nil, fmt.Errorf("invalid selector \"%s\": index starts from 1", expression)
+			}
+		}
+		if step.name == "*" {
+			selector.wildcard = true
+		}
+		selector.steps = append(selector.steps, step)
+	}
+
+	return selector, nil
+}
+
+func stripXMLPrefix(name string) string {
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// selectNodes returns nodes matching selector steps.
+func (selector *xmlSelector) selectNodes(root *xmlNode) []*xmlNode {
+	nodes := []*xmlNode{root}
+	for i := range selector.steps {
+		step := &selector.steps[i]
+		var next []*xmlNode
+		for _, node := range nodes {
+			position := 0
+			for _, child := range node.children {
+				if !step.match(child) {
+					continue
+				}
+				position++
+				if step.index == 0 || step.index == position {
+					next = append(next, child)
+				}
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// Select returns matching values. Without wildcard, only the first match is returned.
+func (selector *xmlSelector) Select(root *xmlNode) []xmlMatch {
+	var matches []xmlMatch
+	usedKeys := map[string]int{}
+	for _, node := range selector.selectNodes(root) {
+		value := node.text
+		if selector.attribute != "" {
+			var ok bool
+			if value, ok = node.attr(selector.attribute); !ok {
+				continue
+			}
+		}
+
+		key := node.key()
+		if usedKeys[key]++; usedKeys[key] > 1 {
+			key = fmt.Sprintf("%s.%d", key, usedKeys[key]-1)
+		}
+		matches = append(matches, xmlMatch{key: key, value: value})
+		if !selector.wildcard {
+			break
+		}
+	}
+	return matches
+}
+
+// extractXMLFields write values matching selectors into fields, or all values when there is no selector.
+func extractXMLFields(fields map[string]string, root *xmlNode, selectors map[string]*xmlSelector) {
+	if len(selectors) == 0 {
+		flattenXMLNode(fields, "", root)
+		return
+	}
+
+	for internalFieldName, selector := range selectors {
+		for _, match := range selector.Select(root) {
+			if selector.wildcard {
+				fields[internalFieldName+"."+match.key] = match.value
+				continue
+			}
+			fields[internalFieldName] = match.value
+		}
+	}
+}
+
+// flattenXMLNode capture attributes and texts of node and its children as dotted keys,
+// e.g. "level" (attribute of record element), "message" (text of child) or "locationInfo.line".
+func flattenXMLNode(fields map[string]string, prefix string, node *xmlNode) {
+	join := func(name string) string {
+		if prefix == "" {
+			return name
+		}
+		return prefix + "." + name
+	}
+
+	for _, attr := range node.attrKeys {
+		fields[join(attr)] = node.attrs[attr]
+	}
+	if node.text != "" && prefix != "" {
+		fields[prefix] = node.text
+	}
+
+	usedKeys := map[string]int{}
+	for _, child := range node.children {
+		key := child.key()
+		if usedKeys[key]++; usedKeys[key] > 1 {
+			key = fmt.Sprintf("%s.%d", key, usedKeys[key]-1)
+		}
+		flattenXMLNode(fields, join(key), child)
+	}
+}
+
+// xmlStreamAssembler extract elements of a name from a stream, regardless of line boundaries.
+// Text outside elements is skipped.
+type xmlStreamAssembler struct {
+	start *regexp.Regexp
+	end   *regexp.Regexp
+
+	buffer       strings.Builder
+	lines        int
+	inRecord     bool
+	startTagOpen bool
+}
+
+func newXMLStreamAssembler(element string) *xmlStreamAssembler {
+	name := regexp.QuoteMeta(stripXMLPrefix(element))
+	return &xmlStreamAssembler{
+		start: regexp.MustCompile(`<(?:[\w.\-]+:)?` + name + `(?:[\s/>]|$)`),
+		end:   regexp.MustCompile(`</(?:[\w.\-]+:)?` + name + `\s*>`),
+	}
+}
+
+func (a *xmlStreamAssembler) push(line string) []string {
+	var records []string
+	if a.inRecord {
+		if a.lines >= recordMaxLines {
+			records = a.flush()
+		} else {
+			a.buffer.WriteByte('\n')
+			a.lines++
+		}
+	}
+
+	text := line
+	for text != "" {
+		if !a.inRecord {
+			location := a.start.FindStringIndex(text)
+			if location == nil {
+				break
+			}
+			text = text[location[0]:]
+			a.inRecord, a.startTagOpen, a.lines = true, true, 1
+		}
+
+		if a.startTagOpen {
+			end := strings.IndexByte(text, '>')
+			if end < 0 {
+				a.buffer.WriteString(text)
+				break
+			}
+			a.buffer.WriteString(text[:end+1])
+			a.startTagOpen = false
+			// self-closing element
+			selfClosing := end > 0 && text[end-1] == '/'
+			text = text[end+1:]
+			if selfClosing {
+				records = append(records, a.flush()...)
+				continue
+			}
+		}
+
+		location := a.end.FindStringIndex(text)
+		if location == nil {
+			a.buffer.WriteString(text)
+			break
+		}
+		a.buffer.WriteString(text[:location[1]])
+		records = append(records, a.flush()...)
+		text = text[location[1]:]
+	}
+
+	return records
+}
+
+func (a *xmlStreamAssembler) flush() []string {
+	if !a.inRecord {
+		return nil
+	}
+	record := a.buffer.String()
+	a.buffer.Reset()
+	a.inRecord, a.startTagOpen, a.lines = false, false, 0
+	return []string{record}
+}
@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestXMLStreamAssembler(t *testing.T) {
+	tests := map[string]struct {
+		element string
+		lines   []string
+		records []string
+	}{
+		"one element per line": {
+			element: "Event",
+			lines:   []string{`<Event><EventID>1</EventID></Event>`, `<Event><EventID>2</EventID></Event>`},
+			records: []string{`<Event><EventID>1</EventID></Event>`, `<Event><EventID>2</EventID></Event>`},
+		},
+		"element split across lines": {
+			element: "Event",
+			lines:   []string{`<Event>`, `  <EventID>1</EventID>`, `</Event>`},
+			records: []string{"<Event>\n  <EventID>1</EventID>\n</Event>"},
+		},
+		"several elements on one line": {
+			element: "Event",
+			lines:   []string{`<Event><EventID>1</EventID></Event><Event><EventID>2</EventID></Event>`},
+			records: []string{`<Event><EventID>1</EventID></Event>`, `<Event><EventID>2</EventID></Event>`},
+		},
+		"start tag split across lines": {
+			element: "Event",
+			lines:   []string{`<Event`, `  xmlns="http://schemas.microsoft.com/win/2004/08/events/event">`, `<EventID>1</EventID></Event>`},
+			records: []string{"<Event\n  xmlns=\"http://schemas.microsoft.com/win/2004/08/events/event\">\n<EventID>1</EventID></Event>"},
+		},
+		"surrounding text is skipped": {
+			element: "Event",
+			lines:   []string{`<?xml version="1.0"?>`, `<Events>`, `<Event><EventID>1</EventID></Event>`, `</Events>`},
+			records: []string{`<Event><EventID>1</EventID></Event>`},
+		},
+		"elements with a longer name are not records": {
+			element: "Event",
+			lines:   []string{`<EventData>x</EventData>`, `<Event><EventData>y</EventData></Event>`},
+			records: []string{`<Event><EventData>y</EventData></Event>`},
+		},
+		"namespace prefix": {
+			element: "log4j:event",
+			lines:   []string{`<log4j:event level="ERROR">`, `<log4j:message>oops</log4j:message>`, `</log4j:event>`},
+			records: []string{"<log4j:event level=\"ERROR\">\n<log4j:message>oops</log4j:message>\n</log4j:event>"},
+		},
+		"element without prefix matches prefixed tags": {
+			element: "event",
+			lines:   []string{`<log4j:event level="INFO"></log4j:event>`},
+			records: []string{`<log4j:event level="INFO"></log4j:event>`},
+		},
+		"self-closing elements": {
+			element: "row",
+			lines:   []string{`<rows><row id="1"/><row id="2" /></rows>`},
+			records: []string{`<row id="1"/>`, `<row id="2" />`},
+		},
+		"pending element is flushed": {
+			element: "Event",
+			lines:   []string{`<Event><EventID>1</EventID></Event>`, `<Event><EventID>2`},
+			records: []string{`<Event><EventID>1</EventID></Event>`, `<Event><EventID>2`},
+		},
+		"no element": {
+			element: "Event",
+			lines:   []string{`<?xml version="1.0"?>`, `garbage`},
+			records: nil,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			records := assembleTestRecords(newXMLStreamAssembler(test.element), test.lines)
+			if !reflect.DeepEqual(records, test.records) {
+				t.Errorf("records = %q, want %q", records, test.records)
+			}
+		})
+	}
+}
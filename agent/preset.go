@@ -0,0 +1,115 @@
+package agent
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"gobana-agent/core"
+)
+
+// Parser presets for common log formats
+//
+//go:embed resources/presets.yaml
+var parserPresetsFile []byte
+
+// parserPreset contains parser settings provided by a preset.
+type parserPreset struct {
+	Mode         string                   `yaml:"mode"`
+	RegexPattern string                   `yaml:"regex_pattern"`
+	JSONFields   map[string]string        `yaml:"json_fields"`
+	XMLElement   string                   `yaml:"xml_element"`
+	XMLFields    map[string]string        `yaml:"xml_fields"`
+	DateExtract  DateExtractConfigStruct  `yaml:"date_extract"`
+	Severity     SeverityConfigStruct     `yaml:"severity"`
+	SubParsers   []*SubParserConfigStruct `yaml:"sub_parsers"`
+}
+
+// loadParserPresets decode embedded presets. Presets are decoded on each call
+// so parsers using the same preset do not share sub-parsers.
+func loadParserPresets() (map[string]*parserPreset, error) {
+	presets := map[string]*parserPreset{}
+	if err := yaml.Unmarshal(parserPresetsFile, &presets); err != nil {
+		return nil, fmt.Errorf("unable to decode parser presets: %w", err)
+	}
+	return presets, nil
+}
+
+// applyPreset fill parser settings that are not defined in configuration with preset ones.
+func (s *ParserConfigStruct) applyPreset() error {
+	presets, err := loadParserPresets()
+	if err != nil {
+		return err
+	}
+	preset, ok := presets[s.Preset]
+	if !ok {
+		return fmt.Errorf("parser \"%s\": unknown preset \"%s\" (available: %s)",
+			s.Name, s.Preset, strings.Join(core.SortedKeys(presets), ", "))
+	}
+
+	if s.Mode == "" {
+		s.Mode = preset.Mode
+	}
+	if s.RegexPattern == "" {
+		s.RegexPattern = preset.RegexPattern
+	}
+	if len(preset.JSONFields) > 0 {
+		if s.JSONFields == nil {
+			s.JSONFields = make(map[string]string, len(preset.JSONFields))
+		}
+		for internalFieldName, jsonField := range preset.JSONFields {
+			if _, ok := s.JSONFields[internalFieldName]; !ok {
+				s.JSONFields[internalFieldName] = jsonField
+			}
+		}
+	}
+	if s.XMLElement == "" {
+		s.XMLElement = preset.XMLElement
+	}
+	if len(preset.XMLFields) > 0 {
+		if s.XMLFields == nil {
+			s.XMLFields = make(map[string]string, len(preset.XMLFields))
+		}
+		for internalFieldName, xmlField := range preset.XMLFields {
+			if _, ok := s.XMLFields[internalFieldName]; !ok {
+				s.XMLFields[internalFieldName] = xmlField
+			}
+		}
+	}
+	if s.DateExtract.Field == "" {
+		s.DateExtract.Field = preset.DateExtract.Field
+		s.DateExtract.Format = preset.DateExtract.Format
+		s.DateExtract.Formats = preset.DateExtract.Formats
+	}
+	if s.Severity.Field == "" {
+		s.Severity.Field = preset.Severity.Field
+	}
+	if len(preset.Severity.Mapping) > 0 {
+		if s.Severity.Mapping == nil {
+			s.Severity.Mapping = make(map[string]string, len(preset.Severity.Mapping))
+		}
+		for value, severity := range preset.Severity.Mapping {
+			if _, ok := s.Severity.Mapping[value]; !ok {
+				s.Severity.Mapping[value] = severity
+			}
+		}
+	}
+	for _, subParser := range preset.SubParsers {
+		if !s.hasSubParser(subParser.Field) {
+			s.SubParsers = append(s.SubParsers, subParser)
+		}
+	}
+
+	return nil
+}
+
+func (s *ParserConfigStruct) hasSubParser(field string) bool {
+	for _, subParser := range s.SubParsers {
+		if subParser.Field == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,264 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nxadm/tail"
+
+	"gobana-agent/core"
+)
+
+func TestParserPresets(t *testing.T) {
+	tests := map[string]struct {
+		lines    []string
+		fields   map[string]string
+		date     time.Time
+		severity string
+	}{
+		"nginx_access": {
+			lines: []string{
+				`203.0.113.7 - - [10/Oct/2024:13:55:36 +0200] "GET /api/orders?page=2 HTTP/1.1" 502 157 "https://shop.example.com/" "Mozilla/5.0 (X11; Linux x86_64)"`,
+			},
+			fields: map[string]string{
+				"remote_addr": "203.0.113.7", "remote_user": "-", "method": "GET", "url": "/api/orders?page=2",
+				"protocol": "HTTP/1.1", "status": "502", "body_bytes_sent": "157", "http_referer": "https://shop.example.com/",
+				"user_agent": "Mozilla/5.0 (X11; Linux x86_64)",
+			},
+			date: time.Date(2024, 10, 10, 11, 55, 36, 0, time.UTC),
+		},
+		"nginx_error": {
+			lines: []string{
+				`2024/10/10 13:55:36 [error] 1234#1234: *5678 connect() failed (111: Connection refused) while connecting to upstream, client: 203.0.113.7, server: shop.example.com, request: "GET /api/orders HTTP/1.1", upstream: "http://127.0.0.1:8080/api/orders", host: "shop.example.com"`,
+			},
+			fields: map[string]string{
+				"level": "error", "pid": "1234", "tid": "1234", "connection_id": "5678",
+				"message": "connect() failed (111: Connection refused) while connecting to upstream",
+				"client":  "203.0.113.7", "server": "shop.example.com", "request": "GET /api/orders HTTP/1.1",
+			},
+			date:     time.Date(2024, 10, 10, 13, 55, 36, 0, time.UTC),
+			severity: "error",
+		},
+		"apache_combined": {
+			lines: []string{
+				`192.0.2.10 - frank [10/Oct/2024:13:55:36 -0700] "POST /login HTTP/1.1" 401 2326 "http://www.example.com/start.html" "Mozilla/4.08 [en] (Win98; I ;Nav)"`,
+			},
+			fields: map[string]string{
+				"remote_addr": "192.0.2.10", "ident": "-", "remote_user": "frank", "method": "POST", "url": "/login",
+				"status": "401", "bytes_sent": "2326", "http_referer": "http://www.example.com/start.html",
+				"user_agent": "Mozilla/4.08 [en] (Win98; I ;Nav)",
+			},
+			date: time.Date(2024, 10, 10, 20, 55, 36, 0, time.UTC),
+		},
+		"symfony": {
+			lines: []string{
+				`[2024-10-10T13:55:36.123456+00:00] request.CRITICAL: Uncaught PHP Exception RuntimeException: "Order not found" at /var/www/src/Controller/OrderController.php line 42 {"exception":"[object] (RuntimeException(code: 0): Order not found at /var/www/src/Controller/OrderController.php:42)","order_id":5} []`,
+			},
+			fields: map[string]string{
+				"channel": "request", "level": "CRITICAL",
+				"message":          `Uncaught PHP Exception RuntimeException: "Order not found" at /var/www/src/Controller/OrderController.php line 42`,
+				"context.order_id": "5",
+			},
+			date:     time.Date(2024, 10, 10, 13, 55, 36, 123456000, time.UTC),
+			severity: "critical",
+		},
+		"laravel": {
+			lines: []string{
+				`[2024-10-10 13:55:36] production.ERROR: Payment gateway timeout {"userId":42,"gateway":"stripe"}`,
+			},
+			fields: map[string]string{
+				"environment": "production", "level": "ERROR", "message": "Payment gateway timeout",
+				"context.userId": "42", "context.gateway": "stripe",
+			},
+			date:     time.Date(2024, 10, 10, 13, 55, 36, 0, time.UTC),
+			severity: "error",
+		},
+		"postgresql": {
+			lines: []string{
+				`2024-10-10 13:55:36.200 UTC [1234] app@shop ERROR:  relation "order" does not exist at character 15`,
+				`2024-10-10 13:55:36.200 UTC [1234] app@shop STATEMENT:  SELECT * FROM order`,
+				`2024-10-10 13:55:37.000 UTC [1235] app@shop LOG:  duration: 2.500 ms  statement: SELECT 1`,
+			},
+			fields: map[string]string{
+				"pid": "1234", "user": "app", "database": "shop", "level": "ERROR",
+				"message":   `relation "order" does not exist at character 15`,
+				"statement": "SELECT * FROM order", "query": "SELECT * FROM order",
+			},
+			date:     time.Date(2024, 10, 10, 13, 55, 36, 200000000, time.UTC),
+			severity: "error",
+		},
+		"mysql_slow": {
+			lines: []string{
+				`# Time: 2024-10-10T13:55:36.123456Z`,
+				`# User@Host: app[app] @ localhost [127.0.0.1]  Id:    12`,
+				`# Query_time: 2.000123  Lock_time: 0.000100 Rows_sent: 1  Rows_examined: 100000`,
+				`use shop;`,
+				`SET timestamp=1728568536;`,
+				`SELECT * FROM orders WHERE id = 5;`,
+			},
+			fields: map[string]string{
+				"user": "app", "host": "localhost", "ip": "127.0.0.1", "thread_id": "12", "query_time": "2.000123",
+				"rows_examined": "100000", "database": "shop", "query": "SELECT * FROM orders WHERE id = 5;",
+			},
+			date: time.Date(2024, 10, 10, 13, 55, 36, 123456000, time.UTC),
+		},
+		"mysql_error": {
+			lines: []string{
+				`2024-10-10T13:55:36.123456Z 8 [Warning] [MY-010055] [Server] IP address '203.0.113.7' could not be resolved: Name or service not known`,
+			},
+			fields: map[string]string{
+				"thread": "8", "level": "Warning", "error_code": "MY-010055", "subsystem": "Server",
+				"message": "IP address '203.0.113.7' could not be resolved: Name or service not known",
+			},
+			date:     time.Date(2024, 10, 10, 13, 55, 36, 123456000, time.UTC),
+			severity: "warning",
+		},
+		"redis": {
+			lines: []string{
+				`1234:M 10 Oct 2024 13:55:36.123 * Background saving started by pid 5678`,
+			},
+			fields: map[string]string{
+				"pid": "1234", "role": "M", "level": "*", "message": "Background saving started by pid 5678",
+			},
+			date:     time.Date(2024, 10, 10, 13, 55, 36, 123000000, time.UTC),
+			severity: "notice",
+		},
+		"haproxy": {
+			lines: []string{
+				`Oct 10 13:55:36 lb1 haproxy[14389]: 10.0.1.2:33317 [10/Oct/2024:13:55:36.159] http-in static/srv1 10/0/30/69/109 200 2750 - - ---- 1/1/1/1/0 0/0 "GET /index.html HTTP/1.1"`,
+			},
+			fields: map[string]string{
+				"pid": "14389", "client_ip": "10.0.1.2", "client_port": "33317", "frontend": "http-in", "backend": "static",
+				"server": "srv1", "time_total": "109", "status": "200", "bytes_read": "2750", "termination_state": "----",
+				"method": "GET", "url": "/index.html",
+			},
+			date: time.Date(2024, 10, 10, 13, 55, 36, 159000000, time.UTC),
+		},
+		"systemd_journal": {
+			lines: []string{
+				`{"__REALTIME_TIMESTAMP":"1728568536123456","PRIORITY":"3","SYSLOG_IDENTIFIER":"sshd","_SYSTEMD_UNIT":"ssh.service","_HOSTNAME":"web1","_PID":"812","_UID":"0","_TRANSPORT":"syslog","MESSAGE":"error: kex_exchange_identification: Connection closed by remote host"}`,
+			},
+			fields: map[string]string{
+				"message": "error: kex_exchange_identification: Connection closed by remote host", "priority": "3",
+				"identifier": "sshd", "unit": "ssh.service", "hostname": "web1", "pid": "812", "uid": "0", "transport": "syslog",
+			},
+			date:     time.Date(2024, 10, 10, 13, 55, 36, 123456000, time.UTC),
+			severity: "error",
+		},
+		"auditd": {
+			lines: []string{
+				`type=SYSCALL msg=audit(1728568536.243:24287): arch=c000003e syscall=59 success=yes exit=0 ppid=1001 pid=1002 auid=1000 uid=0 comm="cat" exe="/usr/bin/cat" key="shadow"`,
+				`type=EXECVE msg=audit(1728568536.243:24287): argc=2 a0="cat" a1="/etc/shadow"`,
+				`type=CWD msg=audit(1728568536.243:24287): cwd="/root"`,
+				`type=PATH msg=audit(1728568536.243:24287): item=0 name="/etc/shadow" inode=1234 mode=0100640`,
+				`type=EOE msg=audit(1728568536.243:24287):`,
+			},
+			fields: map[string]string{
+				"serial": "24287", "type": "SYSCALL", "syscall": "59", "success": "yes", "comm": "cat", "exe": "/usr/bin/cat",
+				"key": "shadow", "command": "cat /etc/shadow", "cwd": "/root", "paths": "/etc/shadow",
+				"record_types": "SYSCALL,EXECVE,CWD,PATH",
+			},
+			date: time.Date(2024, 10, 10, 13, 55, 36, 243000000, time.UTC),
+		},
+		"log4j_xml": {
+			lines: []string{
+				`<log4j:event logger="com.example.OrderService" timestamp="1728568536123" level="ERROR" thread="http-nio-8080-exec-1">`,
+				`<log4j:message><![CDATA[Order 5 not found]]></log4j:message>`,
+				`<log4j:locationInfo class="com.example.OrderService" method="find" file="OrderService.java" line="42"/>`,
+				`</log4j:event>`,
+			},
+			fields: map[string]string{
+				"logger": "com.example.OrderService", "level": "ERROR", "thread": "http-nio-8080-exec-1",
+				"message": "Order 5 not found", "class": "com.example.OrderService", "method": "find",
+				"file": "OrderService.java", "line": "42",
+			},
+			date:     time.Date(2024, 10, 10, 13, 55, 36, 123000000, time.UTC),
+			severity: "error",
+		},
+		"windows_event": {
+			lines: []string{
+				`<Event xmlns="http://schemas.microsoft.com/win/2004/08/events/event"><System><Provider Name="Service Control Manager"/>`,
+				`<EventID>7034</EventID><Level>2</Level><Task>0</Task><Keywords>0x8080000000000000</Keywords>`,
+				`<TimeCreated SystemTime="2024-10-10T13:55:36.1234567Z"/><EventRecordID>4242</EventRecordID>`,
+				`<Channel>System</Channel><Computer>WEB1</Computer><Security/></System>`,
+				`<EventData><Data Name="param1">Print Spooler</Data><Data Name="param2">1</Data></EventData></Event>`,
+			},
+			fields: map[string]string{
+				"provider": "Service Control Manager", "event_id": "7034", "level": "2", "record_id": "4242",
+				"channel": "System", "computer": "WEB1",
+			},
+			date:     time.Date(2024, 10, 10, 13, 55, 36, 123456700, time.UTC),
+			severity: "error",
+		},
+	}
+
+	presets, err := loadParserPresets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name := range presets {
+		if _, ok := tests[name]; !ok {
+			t.Errorf("preset %s has no test", name)
+		}
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := mustReadTestConfig(t, fmt.Sprintf(`
+application: test
+smtp:
+  from_email: "gobana@example.com"
+parsers:
+  - name: %s
+    preset: %s
+    files_included: ["/var/log/%s.log"]
+`, name, name, name))
+			fileWatcher := &currentWatching{parser: config.Parsers[0], fileName: "/var/log/" + name + ".log"}
+			watcher := &WatcherProcess{
+				regexCache:  map[string]*regexp.Regexp{},
+				errorLogger: core.NewLogRateLimiter(core.Logger, time.Minute),
+			}
+
+			records := test.lines
+			if assembler := newRecordAssembler(fileWatcher.parser); assembler != nil {
+				records = nil
+				for _, line := range test.lines {
+					records = append(records, assembler.push(line)...)
+				}
+				records = append(records, assembler.flush()...)
+			}
+			if len(records) == 0 {
+				t.Fatalf("no record assembled")
+			}
+
+			captureDate := time.Date(2024, 10, 11, 0, 0, 0, 0, time.UTC)
+			entry, err := watcher.handleLine(fileWatcher, &tail.Line{Text: records[0], Time: captureDate})
+			if err != nil {
+				t.Fatalf("unexpected error : %s", err)
+			}
+
+			for field, expected := range test.fields {
+				if value := entry.Fields[field]; value != expected {
+					t.Errorf("field %s = %q, want %q", field, value, expected)
+				}
+			}
+			if !entry.Date.Equal(test.date) {
+				t.Errorf("date = %s, want %s", entry.Date, test.date)
+			}
+			// presets without level field get the default severity
+			expectedSeverity := test.severity
+			if expectedSeverity == "" {
+				expectedSeverity = severityUnknown
+			}
+			if severity := entry.Fields[fieldNameSeverity]; severity != expectedSeverity {
+				t.Errorf("severity = %q, want %q", severity, expectedSeverity)
+			}
+			if strings.Contains(entry.Raw, "\n") != (len(test.lines) > 1) {
+				t.Errorf("record must contain all lines of the sample, got %q", entry.Raw)
+			}
+		})
+	}
+}
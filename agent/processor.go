@@ -0,0 +1,256 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gobana-agent/core"
+)
+
+const (
+	processorLogPrefix = "processor"
+
+	processorTypeRename    = "rename"
+	processorTypeDrop      = "drop"
+	processorTypeLowercase = "lowercase"
+	processorTypeUppercase = "uppercase"
+	processorTypeAdd       = "add"
+	processorTypeExtract   = "extract"
+	processorTypeSplit     = "split"
+	processorTypeConcat    = "concat"
+
+	processorOnErrorIgnore = "ignore"
+	processorOnErrorStop   = "stop"
+	processorOnErrorDrop   = "drop"
+)
+
+// processor reshape an entry, it is compiled from a ProcessorConfigStruct.
+type processor interface {
+	process(entry *core.Entry) error
+}
+
+// applyProcessors run parser processors then global processors on entry.
+// It returns false if the entry must be dropped.
+func applyProcessors(parser *ParserConfigStruct, entry *core.Entry) bool {
+	for _, processors := range [][]*ProcessorConfigStruct{parser.Processors, AppConfig.Processors} {
+		keep, stop := runProcessors(processors, entry)
+		if !keep {
+			return false
+		}
+		if stop {
+			break
+		}
+	}
+
+	return true
+}
+
+// runProcessors run processors in order.
+// It returns keep=false if the entry must be dropped and stop=true if the pipeline must be stopped.
+func runProcessors(processors []*ProcessorConfigStruct, entry *core.Entry) (keep, stop bool) {
+	for i, processorConfig := range processors {
+		err := processorConfig.processor.process(entry)
+		if err == nil {
+			continue
+		}
+
+		core.Logger.Debugf(
+			processorLogPrefix, "Processor #%d (%s) failed on parser \"%s\": %s",
+			i, processorConfig.Type, entry.Metadata.Parser, err,
+		)
+		switch processorConfig.OnError {
+		case processorOnErrorDrop:
+			return false, true
+		case processorOnErrorStop:
+			return true, true
+		}
+	}
+
+	return true, false
+}
+
+func compileProcessor(config *ProcessorConfigStruct) (processor, error) {
+	fields := config.Fields
+	if config.Field != "" {
+		fields = append([]string{config.Field}, fields...)
+	}
+
+	switch config.Type {
+	case processorTypeRename:
+		return &renameProcessor{field: config.Field, target: config.Target}, nil
+	case processorTypeDrop:
+		return &dropProcessor{fields: fields}, nil
+	case processorTypeLowercase:
+		return &caseProcessor{fields: fields, convert: strings.ToLower}, nil
+	case processorTypeUppercase:
+		return &caseProcessor{fields: fields, convert: strings.ToUpper}, nil
+	case processorTypeAdd:
+		return &addProcessor{target: config.Target, value: config.Value}, nil
+	case processorTypeExtract:
+		regex, err := regexp.Compile(config.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern is invalid: %w", err)
+		}
+		return &extractProcessor{field: config.Field, regex: regex}, nil
+	case processorTypeSplit:
+		return &splitProcessor{field: config.Field, separator: config.Separator, target: config.Target, targets: config.Targets}, nil
+	case processorTypeConcat:
+		return &concatProcessor{fields: fields, separator: config.Separator, target: config.Target}, nil
+	case processorTypeGeoIP:
+		return compileGeoIPProcessor(config)
+	case processorTypeUserAgent:
+		return compileUserAgentProcessor(config)
+	case processorTypeLookup:
+		return compileLookupProcessor(config)
+	case processorTypeException:
+		return compileExceptionProcessor(config)
+	default:
+		return nil, fmt.Errorf("unknown processor type %s", config.Type)
+	}
+}
+
+func fieldValue(entry *core.Entry, field string) (string, error) {
+	value, ok := entry.Fields[field]
+	if !ok {
+		return "", fmt.Errorf("field \"%s\" not exists", field)
+	}
+	return value, nil
+}
+
+// renameProcessor move field to target.
+type renameProcessor struct {
+	field  string
+	target string
+}
+
+func (p *renameProcessor) process(entry *core.Entry) error {
+	value, err := fieldValue(entry, p.field)
+	if err != nil {
+		return err
+	}
+	delete(entry.Fields, p.field)
+	entry.Fields[p.target] = value
+	return nil
+}
+
+// dropProcessor remove fields.
+type dropProcessor struct {
+	fields []string
+}
+
+func (p *dropProcessor) process(entry *core.Entry) error {
+	for _, field := range p.fields {
+		delete(entry.Fields, field)
+	}
+	return nil
+}
+
+// caseProcessor convert the case of fields.
+type caseProcessor struct {
+	fields  []string
+	convert func(string) string
+}
+
+func (p *caseProcessor) process(entry *core.Entry) error {
+	for _, field := range p.fields {
+		value, err := fieldValue(entry, field)
+		if err != nil {
+			return err
+		}
+		entry.Fields[field] = p.convert(value)
+	}
+	return nil
+}
+
+// addProcessor set a static value.
+type addProcessor struct {
+	target string
+	value  string
+}
+
+func (p *addProcessor) process(entry *core.Entry) error {
+	entry.Fields[p.target] = p.value
+	return nil
+}
+
+// extractProcessor capture named groups of a regex applied on a field.
+type extractProcessor struct {
+	field string
+	regex *regexp.Regexp
+}
+
+func (p *extractProcessor) process(entry *core.Entry) error {
+	value, err := fieldValue(entry, p.field)
+	if err != nil {
+		return err
+	}
+	matches := p.regex.FindStringSubmatch(value)
+	if len(matches) == 0 {
+		return fmt.Errorf("field \"%s\" not match pattern", p.field)
+	}
+	for i, name := range p.regex.SubexpNames() {
+		if i == 0 || name == "" {
+			continue
+		}
+		entry.Fields[name] = matches[i]
+	}
+	return nil
+}
+
+// splitProcessor split a field into targets, or into "<target>.<index>" when no targets are defined.
+type splitProcessor struct {
+	field     string
+	separator string
+	target    string
+	targets   []string
+}
+
+func (p *splitProcessor) process(entry *core.Entry) error {
+	value, err := fieldValue(entry, p.field)
+	if err != nil {
+		return err
+	}
+
+	if len(p.targets) > 0 {
+		parts := strings.SplitN(value, p.separator, len(p.targets))
+		for i, target := range p.targets {
+			if i < len(parts) {
+				entry.Fields[target] = parts[i]
+			} else {
+				entry.Fields[target] = ""
+			}
+		}
+		return nil
+	}
+
+	target := p.target
+	if target == "" {
+		target = p.field
+	}
+	for i, part := range strings.Split(value, p.separator) {
+		entry.Fields[target+"."+strconv.Itoa(i)] = part
+	}
+	return nil
+}
+
+// concatProcessor join fields into target.
+type concatProcessor struct {
+	fields    []string
+	separator string
+	target    string
+}
+
+func (p *concatProcessor) process(entry *core.Entry) error {
+	values := make([]string, 0, len(p.fields))
+	for _, field := range p.fields {
+		value, err := fieldValue(entry, field)
+		if err != nil {
+			return err
+		}
+		values = append(values, value)
+	}
+	entry.Fields[p.target] = strings.Join(values, p.separator)
+	return nil
+}
@@ -0,0 +1,359 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gobana-agent/core"
+)
+
+const (
+	processorTypeException = "exception"
+
+	exceptionDefaultTarget = "exception"
+	// number of application frames used to compute fingerprint
+	exceptionFingerprintFrames = 3
+
+	// fieldNameKeyLine contains the line summarising the entry, displayed first in notifications
+	fieldNameKeyLine = "_key_line"
+)
+
+// exceptionFrame is a stack frame, function is used for fingerprint and location (file and line) for display.
+type exceptionFrame struct {
+	function string
+	location string
+}
+
+var exceptionLineNumberRegex = regexp.MustCompile(`(?::\d+)+$| on line \d+$`)
+
+// fingerprintKey returns function of frame, or its location without line number.
+func (frame exceptionFrame) fingerprintKey() string {
+	if frame.function != "" {
+		return frame.function
+	}
+	return exceptionLineNumberRegex.ReplaceAllString(frame.location, "")
+}
+
+func (frame exceptionFrame) String() string {
+	switch {
+	case frame.function == "":
+		return frame.location
+	case frame.location == "":
+		return frame.function
+	default:
+		return fmt.Sprintf("%s (%s)", frame.function, frame.location)
+	}
+}
+
+// exception is the result of a stack trace analysis.
+type exception struct {
+	language string
+	class    string
+	message  string
+	cause    string
+	frames   []exceptionFrame
+}
+
+// exceptionAnalyzer detect and parse a stack trace of a language.
+type exceptionAnalyzer struct {
+	language string
+	detect   *regexp.Regexp
+	parse    func(text string) *exception
+	// frames of libraries and runtime, ignored to find application frames
+	vendorFrame *regexp.Regexp
+}
+
+// pythonTracebackHeader begins each traceback of a (possibly chained) Python exception
+const pythonTracebackHeader = "Traceback (most recent call last):"
+
+var (
+	pythonFrameRegex     = regexp.MustCompile(`(?m)^\s*File "([^"]+)", line (\d+), in (\S+)`)
+	pythonExceptionRegex = regexp.MustCompile(`^([A-Za-z_][\w.]*)(?:: (.*))?$`)
+
+	goPanicRegex = regexp.MustCompile(`(?m)^(panic|fatal error): (.*)$`)
+	goFrameRegex = regexp.MustCompile(`(?m)^([\w./*()\-]+)\([^)\n]*\)\n\s+(\S+:\d+)`)
+
+	javaExceptionRegex = regexp.MustCompile(`(?m)^(?:Exception in thread "[^"]*" )?([a-zA-Z_$][\w$]*(?:\.[a-zA-Z_$][\w$]*)+)(?:: (.*))?$`)
+	javaCauseRegex     = regexp.MustCompile(`(?m)^Caused by: ([\w$.]+)(?:: (.*))?$`)
+	javaFrameRegex     = regexp.MustCompile(`(?m)^\s+at ([\w$.<>/]+)\(([^)]*)\)`)
+
+	nodeExceptionRegex = regexp.MustCompile(`(?m)^(?:Uncaught )?(\w*(?:Error|Exception))(?: \[\w+\])?: (.*)$`)
+	nodeFrameRegex     = regexp.MustCompile(`(?m)^\s+at (?:(?:async )?(\S+(?: \[as \w+\])?) \((.+?)\)|(.+?))$`)
+
+	phpExceptionRegexes = []*regexp.Regexp{
+		// PHP Fatal error:  Uncaught Exception: message in /path/file.php:12
+		regexp.MustCompile(`Uncaught ([\w\\]+): (.*?) in (\S+:\d+)`),
+		// Monolog : [object] (RuntimeException(code: 0): message at /path/file.php:12)
+		regexp.MustCompile(`\[object\] \(([\w\\]+)\(code: -?\d+\): (.*?) at (\S+:\d+)\)`),
+		// PHP Warning:  message in /path/file.php on line 12
+		regexp.MustCompile(`PHP (Fatal error|Parse error|Warning|Notice|Deprecated):\s+(.*?) in (\S+ on line \d+)`),
+	}
+	phpFrameRegex = regexp.MustCompile(`(?m)#\d+ (?:(\S+)\((\d+)\): )?(\S+?)\(`)
+
+	exceptionAnalyzers = []*exceptionAnalyzer{
+		{
+			language:    "python",
+			detect:      regexp.MustCompile(`Traceback \(most recent call last\):`),
+			parse:       parsePythonException,
+			vendorFrame: regexp.MustCompile(`site-packages|dist-packages|/lib/python\d|<frozen `),
+		},
+		{
+			language:    "go",
+			detect:      regexp.MustCompile(`(?m)^(?:panic|fatal error): .*\n(?:.*\n)*?goroutine \d+ \[`),
+			parse:       parseGoException,
+			vendorFrame: regexp.MustCompile(`^(?:runtime|sync|reflect|net/http|testing)\.|/go/pkg/mod/|/usr/local/go/`),
+		},
+		{
+			language: "java",
+			detect:   regexp.MustCompile(`(?m)^\s+at [\w$.<>/]+\([^)]*(?:\.java|\.kt|\.scala|Native Method|Unknown Source)`),
+			parse:    parseJavaException,
+			vendorFrame: regexp.MustCompile(
+				`^(?:java|javax|jdk|sun|com\.sun|kotlin|scala|org\.springframework|org\.apache|org\.hibernate)\.`,
+			),
+		},
+		{
+			language:    "node",
+			detect:      regexp.MustCompile(`(?m)^\s+at .*(?:\.[cm]?js|\.ts|node:[\w/]+):\d+:\d+\)?$`),
+			parse:       parseNodeException,
+			vendorFrame: regexp.MustCompile(`node_modules|node:|\(internal/|^internal/`),
+		},
+		{
+			language: "php",
+			// uncaught exception, Monolog context of an exception, or PHP error
+			detect: regexp.MustCompile(`Uncaught [\w\\]+: ` +
+				`|\[object\] \([\w\\]+\(code: ` +
+				`|PHP (?:Fatal error|Parse error|Warning|Notice|Deprecated):`),
+			parse:       parsePHPException,
+			vendorFrame: regexp.MustCompile(`/vendor/|\[internal function\]`),
+		},
+	}
+)
+
+// exceptionProcessor detect stack traces and add "<target>.language", "<target>.class", "<target>.message",
+// "<target>.cause", "<target>.frame" (top application frame) and "<target>.fingerprint".
+type exceptionProcessor struct {
+	field  string
+	target string
+	// frames of application, when set frames not matching it are ignored
+	applicationFrame *regexp.Regexp
+}
+
+func compileExceptionProcessor(config *ProcessorConfigStruct) (processor, error) {
+	p := &exceptionProcessor{field: config.Field, target: config.Target}
+	if p.target == "" {
+		p.target = exceptionDefaultTarget
+	}
+	if config.Pattern != "" {
+		regex, err := regexp.Compile(config.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern is invalid: %w", err)
+		}
+		p.applicationFrame = regex
+	}
+	return p, nil
+}
+
+func (p *exceptionProcessor) process(entry *core.Entry) error {
+	text := entry.Raw
+	if p.field != "" {
+		value, err := fieldValue(entry, p.field)
+		if err != nil {
+			return err
+		}
+		text = value
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	for _, analyzer := range exceptionAnalyzers {
+		if !analyzer.detect.MatchString(text) {
+			continue
+		}
+		exception := analyzer.parse(text)
+		if exception == nil || exception.class == "" {
+			continue
+		}
+		exception.language = analyzer.language
+		p.addFields(entry, exception, p.applicationFrames(analyzer, exception.frames))
+		return nil
+	}
+
+	// no stack trace found
+	return nil
+}
+
+// applicationFrames returns frames of application code (from the innermost call).
+func (p *exceptionProcessor) applicationFrames(analyzer *exceptionAnalyzer, frames []exceptionFrame) []exceptionFrame {
+	var applicationFrames []exceptionFrame
+	for _, frame := range frames {
+		text := frame.function + " " + frame.location
+		if p.applicationFrame != nil {
+			if p.applicationFrame.MatchString(text) {
+				applicationFrames = append(applicationFrames, frame)
+			}
+			continue
+		}
+		if !analyzer.vendorFrame.MatchString(frame.function) && !analyzer.vendorFrame.MatchString(frame.location) {
+			applicationFrames = append(applicationFrames, frame)
+		}
+	}
+	return applicationFrames
+}
+
+func (p *exceptionProcessor) addFields(entry *core.Entry, exception *exception, applicationFrames []exceptionFrame) {
+	entry.Fields[p.target+".language"] = exception.language
+	entry.Fields[p.target+".class"] = exception.class
+	entry.Fields[p.target+".message"] = exception.message
+	if exception.cause != "" {
+		entry.Fields[p.target+".cause"] = exception.cause
+	}
+
+	keyLine := exception.class
+	if exception.message != "" {
+		keyLine += ": " + exception.message
+	}
+	if len(applicationFrames) > 0 {
+		entry.Fields[p.target+".frame"] = applicationFrames[0].String()
+		keyLine += " at " + applicationFrames[0].String()
+	}
+	entry.Fields[fieldNameKeyLine] = keyLine
+
+	// fingerprint is based on class and functions (not line numbers) so it is stable across deployments
+	hash := sha256.New()
+	hash.Write([]byte(exception.language + "\n" + exception.class))
+	for i, frame := range applicationFrames {
+		if i >= exceptionFingerprintFrames {
+			break
+		}
+		hash.Write([]byte("\n" + frame.fingerprintKey()))
+	}
+	entry.Fields[p.target+".fingerprint"] = hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// parsePythonException parse a traceback, frames are listed from the outermost call.
+func parsePythonException(text string) *exception {
+	start := strings.LastIndex(text, pythonTracebackHeader)
+	if start < 0 {
+		return nil
+	}
+	traceback := text[start:]
+	result := &exception{}
+
+	frameMatches := pythonFrameRegex.FindAllStringSubmatch(traceback, -1)
+	for i := len(frameMatches) - 1; i >= 0; i-- {
+		result.frames = append(result.frames, exceptionFrame{
+			function: frameMatches[i][3],
+			location: frameMatches[i][1] + ":" + frameMatches[i][2],
+		})
+	}
+
+	// exception is the first line after frames, without indentation
+	lines := strings.Split(traceback, "\n")
+	for _, line := range lines[1:] {
+		if line == "" || strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		if matches := pythonExceptionRegex.FindStringSubmatch(line); matches != nil {
+			result.class, result.message = matches[1], matches[2]
+		}
+		break
+	}
+
+	// chained exceptions : the first traceback is the cause
+	if strings.Contains(text[:start], pythonTracebackHeader) {
+		if cause := parsePythonException(text[:start]); cause != nil {
+			result.cause = cause.class
+		}
+	}
+
+	return result
+}
+
+func parseGoException(text string) *exception {
+	matches := goPanicRegex.FindStringSubmatch(text)
+	if matches == nil {
+		return nil
+	}
+	result := &exception{class: matches[1], message: matches[2]}
+	if message, ok := strings.CutPrefix(result.message, "runtime error: "); ok {
+		result.class, result.message = "runtime error", message
+	}
+
+	for _, frameMatches := range goFrameRegex.FindAllStringSubmatch(text[strings.Index(text, matches[0]):], -1) {
+		result.frames = append(result.frames, exceptionFrame{function: frameMatches[1], location: frameMatches[2]})
+	}
+
+	return result
+}
+
+func parseJavaException(text string) *exception {
+	frameIndex := javaFrameRegex.FindStringIndex(text)
+	if frameIndex == nil {
+		// truncated frame, e.g. "at com.example.Foo.bar(Foo.java"
+		return nil
+	}
+	matches := javaExceptionRegex.FindAllStringSubmatchIndex(text[:frameIndex[0]], -1)
+	if matches == nil {
+		return nil
+	}
+	// exception is the last line matching before the first frame
+	last := matches[len(matches)-1]
+	result := &exception{class: text[last[2]:last[3]]}
+	if last[4] >= 0 {
+		result.message = text[last[4]:last[5]]
+	}
+	if causes := javaCauseRegex.FindAllStringSubmatch(text, -1); causes != nil {
+		result.cause = causes[len(causes)-1][1]
+	}
+
+	for _, frameMatches := range javaFrameRegex.FindAllStringSubmatch(text, -1) {
+		result.frames = append(result.frames, exceptionFrame{function: frameMatches[1], location: frameMatches[2]})
+	}
+
+	return result
+}
+
+func parseNodeException(text string) *exception {
+	matches := nodeExceptionRegex.FindStringSubmatch(text)
+	if matches == nil {
+		return nil
+	}
+	result := &exception{class: matches[1], message: matches[2]}
+
+	for _, frameMatches := range nodeFrameRegex.FindAllStringSubmatch(text, -1) {
+		if frameMatches[3] != "" {
+			result.frames = append(result.frames, exceptionFrame{location: frameMatches[3]})
+			continue
+		}
+		result.frames = append(result.frames, exceptionFrame{function: frameMatches[1], location: frameMatches[2]})
+	}
+
+	return result
+}
+
+func parsePHPException(text string) *exception {
+	result := &exception{}
+	for _, regex := range phpExceptionRegexes {
+		if matches := regex.FindStringSubmatch(text); matches != nil {
+			result.class, result.message = matches[1], matches[2]
+			// location of the exception is the innermost frame
+			result.frames = append(result.frames, exceptionFrame{location: matches[3]})
+			break
+		}
+	}
+	if result.class == "" {
+		return nil
+	}
+
+	for _, frameMatches := range phpFrameRegex.FindAllStringSubmatch(text, -1) {
+		location := frameMatches[1]
+		if location != "" {
+			location += ":" + frameMatches[2]
+		}
+		result.frames = append(result.frames, exceptionFrame{function: frameMatches[3], location: location})
+	}
+
+	return result
+}
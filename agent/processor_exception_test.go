@@ -0,0 +1,182 @@
+package agent
+
+import (
+	"testing"
+
+	"gobana-agent/core"
+)
+
+func TestExceptionProcessor(t *testing.T) {
+	tests := map[string]struct {
+		text   string
+		fields map[string]string
+	}{
+		"python traceback": {
+			text: "Traceback (most recent call last):\n" +
+				"  File \"/app/shop/views.py\", line 42, in checkout\n" +
+				"    order = Order.objects.get(pk=pk)\n" +
+				"KeyError: 'order'",
+			fields: map[string]string{
+				"exception.language": "python", "exception.class": "KeyError", "exception.message": "'order'",
+				"exception.frame": "checkout (/app/shop/views.py:42)",
+			},
+		},
+		"python traceback prefixed by log line": {
+			text: "ERROR:root:oops\n" +
+				"Traceback (most recent call last):\n" +
+				"  File \"/app/shop/views.py\", line 42, in checkout\n" +
+				"    order = Order.objects.get(pk=pk)\n" +
+				"ValueError: invalid order",
+			fields: map[string]string{
+				"exception.language": "python", "exception.class": "ValueError", "exception.message": "invalid order",
+				"exception.frame": "checkout (/app/shop/views.py:42)",
+			},
+		},
+		"python chained traceback prefixed by log line": {
+			text: "ERROR:root:oops\n" +
+				"Traceback (most recent call last):\n" +
+				"  File \"/app/shop/db.py\", line 7, in fetch\n" +
+				"KeyError: 'order'\n" +
+				"\n" +
+				"During handling of the above exception, another exception occurred:\n" +
+				"\n" +
+				"Traceback (most recent call last):\n" +
+				"  File \"/app/shop/views.py\", line 42, in checkout\n" +
+				"ValueError: invalid order",
+			fields: map[string]string{
+				"exception.class": "ValueError", "exception.cause": "KeyError", "exception.frame": "checkout (/app/shop/views.py:42)",
+			},
+		},
+		"java stack trace": {
+			text: "java.lang.IllegalStateException: order not found\n" +
+				"\tat com.example.OrderService.find(OrderService.java:42)\n" +
+				"\tat java.base/java.lang.Thread.run(Thread.java:833)",
+			fields: map[string]string{
+				"exception.language": "java", "exception.class": "java.lang.IllegalStateException",
+				"exception.message": "order not found", "exception.frame": "com.example.OrderService.find (OrderService.java:42)",
+			},
+		},
+		"go runtime panic": {
+			text: "panic: runtime error: invalid memory address or nil pointer dereference\n" +
+				"[signal SIGSEGV: segmentation violation code=0x1 addr=0x0 pc=0x4a2b3c]\n" +
+				"\n" +
+				"goroutine 1 [running]:\n" +
+				"runtime.panicmem()\n" +
+				"\t/usr/local/go/src/runtime/panic.go:261 +0x48\n" +
+				"main.(*OrderService).Find(0x0, 0x5)\n" +
+				"\t/app/order.go:42 +0x1d\n" +
+				"main.main()\n" +
+				"\t/app/main.go:12 +0x25\n" +
+				"exit status 2",
+			fields: map[string]string{
+				"exception.language": "go", "exception.class": "runtime error",
+				"exception.message": "invalid memory address or nil pointer dereference",
+				"exception.frame":   "main.(*OrderService).Find (/app/order.go:42)",
+			},
+		},
+		"go custom panic": {
+			text: "panic: order not found\n\ngoroutine 7 [running]:\nmain.checkout()\n\t/app/checkout.go:18 +0x65",
+			fields: map[string]string{
+				"exception.language": "go", "exception.class": "panic", "exception.message": "order not found",
+				"exception.frame": "main.checkout (/app/checkout.go:18)",
+			},
+		},
+		"php uncaught exception": {
+			text: "PHP Fatal error:  Uncaught App\\Exception\\OrderNotFound: Order 5 not found in /var/www/src/OrderService.php:42\n" +
+				"Stack trace:\n" +
+				"#0 /var/www/src/Controller/OrderController.php(12): App\\OrderService->find(5)\n" +
+				"#1 /var/www/vendor/symfony/http-kernel/HttpKernel.php(181): App\\Controller\\OrderController->show()\n" +
+				"#2 {main}\n" +
+				"  thrown in /var/www/src/OrderService.php on line 42",
+			fields: map[string]string{
+				"exception.language": "php", "exception.class": "App\\Exception\\OrderNotFound",
+				"exception.message": "Order 5 not found", "exception.frame": "/var/www/src/OrderService.php:42",
+			},
+		},
+		"php monolog context": {
+			text: `[2024-10-10T13:55:36+00:00] request.CRITICAL: Uncaught PHP Exception RuntimeException: "Order not found" ` +
+				`{"exception":"[object] (RuntimeException(code: 0): Order not found at /var/www/src/OrderService.php:42)"} []`,
+			fields: map[string]string{
+				"exception.language": "php", "exception.class": "RuntimeException", "exception.message": "Order not found",
+				"exception.frame": "/var/www/src/OrderService.php:42",
+			},
+		},
+		"php warning": {
+			text: "PHP Warning:  Undefined variable $order in /var/www/src/Controller.php on line 12",
+			fields: map[string]string{
+				"exception.language": "php", "exception.class": "Warning", "exception.message": "Undefined variable $order",
+				"exception.frame": "/var/www/src/Controller.php on line 12",
+			},
+		},
+		"node error": {
+			text: "TypeError: Cannot read properties of undefined (reading 'id')\n" +
+				"    at Layer.handle [as handle_request] (/app/node_modules/express/lib/router/layer.js:95:5)\n" +
+				"    at OrderService.find (/app/src/orders.js:42:15)\n" +
+				"    at /app/src/server.js:12:5\n" +
+				"    at process.processTicksAndRejections (node:internal/process/task_queues:95:5)",
+			fields: map[string]string{
+				"exception.language": "node", "exception.class": "TypeError",
+				"exception.message": "Cannot read properties of undefined (reading 'id')",
+				"exception.frame":   "OrderService.find (/app/src/orders.js:42:15)",
+			},
+		},
+		"node error without application frame": {
+			text: "Error: connect ECONNREFUSED 127.0.0.1:5432\n" +
+				"    at TCPConnectWrap.afterConnect [as oncomplete] (node:net:1595:16)",
+			fields: map[string]string{
+				"exception.language": "node", "exception.class": "Error", "exception.message": "connect ECONNREFUSED 127.0.0.1:5432",
+				"exception.frame": "",
+			},
+		},
+		"java truncated frame": {
+			text:   "java.lang.IllegalStateException: order not found\n\tat com.foo.Bar.baz(Bar.java",
+			fields: map[string]string{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			p, err := compileExceptionProcessor(&ProcessorConfigStruct{Type: processorTypeException})
+			if err != nil {
+				t.Fatal(err)
+			}
+			entry := &core.Entry{Raw: test.text, Fields: map[string]string{}}
+			if err := p.process(entry); err != nil {
+				t.Fatalf("unexpected error : %s", err)
+			}
+
+			if len(test.fields) == 0 && len(entry.Fields) > 0 {
+				t.Errorf("no field expected, got %v", entry.Fields)
+			}
+			for field, expected := range test.fields {
+				if value := entry.Fields[field]; value != expected {
+					t.Errorf("field %s = %q, want %q", field, value, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestExceptionFingerprint(t *testing.T) {
+	p, err := compileExceptionProcessor(&ProcessorConfigStruct{Type: processorTypeException})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprint := func(text string) string {
+		entry := &core.Entry{Raw: text, Fields: map[string]string{}}
+		if err := p.process(entry); err != nil {
+			t.Fatal(err)
+		}
+		return entry.Fields["exception.fingerprint"]
+	}
+
+	original := fingerprint("panic: order 5 not found\n\ngoroutine 7 [running]:\nmain.checkout()\n\t/app/checkout.go:18 +0x65")
+	moved := fingerprint("panic: order 6 not found\n\ngoroutine 9 [running]:\nmain.checkout()\n\t/app/checkout.go:25 +0x65")
+	other := fingerprint("panic: order 5 not found\n\ngoroutine 7 [running]:\nmain.refund()\n\t/app/refund.go:18 +0x65")
+	if original == "" || original != moved {
+		t.Errorf("fingerprint must ignore messages and line numbers, got %q and %q", original, moved)
+	}
+	if original == other {
+		t.Errorf("fingerprint must depend on frames, got %q twice", other)
+	}
+}
@@ -0,0 +1,227 @@
+package agent
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+
+	"gobana-agent/core"
+)
+
+const (
+	processorTypeGeoIP = "geoip"
+
+	geoIPDefaultTarget  = "geoip"
+	geoIPReloadInterval = 10 * time.Second
+	geoIPLanguage       = "en"
+)
+
+// geoIPRecord contains fields read from City, Country, ASN and ISP databases.
+type geoIPRecord struct {
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	ASN             uint   `maxminddb:"autonomous_system_number"`
+	ASNOrganization string `maxminddb:"autonomous_system_organization"`
+	Organization    string `maxminddb:"organization"`
+}
+
+// geoIPDatabase is a MMDB file reloaded when it is replaced on disk. The file is read in memory rather than
+// mapped, so a file rewritten in place can't fault readers and a previous version stays valid while in use.
+type geoIPDatabase struct {
+	mu       sync.RWMutex
+	path     string
+	reader   *maxminddb.Reader
+	detector *core.FileChangeDetector
+}
+
+func openGeoIPDatabase(path string) (*geoIPDatabase, error) {
+	// detector is created first to not miss a change while the file is read
+	detector := core.NewFileChangeDetector(path, geoIPReloadInterval)
+	reader, err := readGeoIPDatabase(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open geoip database %s: %w", path, err)
+	}
+
+	return &geoIPDatabase{
+		path:     path,
+		reader:   reader,
+		detector: detector,
+	}, nil
+}
+
+func readGeoIPDatabase(path string) (*maxminddb.Reader, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return maxminddb.FromBytes(content)
+}
+
+// reloadIfChanged reload the database if file changed, it returns true if database was reloaded.
+// A file being written is rejected by verification and the previous version is kept.
+func (db *geoIPDatabase) reloadIfChanged() bool {
+	if !db.detector.Changed() {
+		return false
+	}
+
+	reader, err := readGeoIPDatabase(db.path)
+	if err == nil {
+		err = reader.Verify()
+	}
+	if err != nil {
+		core.Logger.Errorf(processorLogPrefix, "Unable to reload geoip database %s, keep previous version: %s", db.path, err)
+		return false
+	}
+
+	// previous reader is not closed : it is in memory and released once no lookup uses it
+	db.mu.Lock()
+	db.reader = reader
+	db.mu.Unlock()
+
+	core.Logger.Infof(processorLogPrefix, "Geoip database %s reloaded", db.path)
+	return true
+}
+
+func (db *geoIPDatabase) lookup(ip net.IP, record *geoIPRecord) error {
+	db.mu.RLock()
+	reader := db.reader
+	db.mu.RUnlock()
+
+	return reader.Lookup(ip, record)
+}
+
+// geoIPProcessor add location and network owner of an ip address field.
+type geoIPProcessor struct {
+	field     string
+	target    string
+	databases []*geoIPDatabase
+	cache     *core.LRUCache[string, map[string]string]
+
+	// cacheMu and generation guard the cache against reloads : results of lookups
+	// started before a reload are not cached
+	cacheMu    sync.Mutex
+	generation uint64
+}
+
+func compileGeoIPProcessor(config *ProcessorConfigStruct) (processor, error) {
+	p := &geoIPProcessor{
+		field:  config.Field,
+		target: config.Target,
+		cache:  core.NewLRUCache[string, map[string]string](config.CacheSize),
+	}
+	if p.target == "" {
+		p.target = geoIPDefaultTarget
+	}
+
+	for _, file := range config.Files {
+		db, err := openGeoIPDatabase(file)
+		if err != nil {
+			return nil, err
+		}
+		p.databases = append(p.databases, db)
+	}
+
+	return p, nil
+}
+
+func (p *geoIPProcessor) process(entry *core.Entry) error {
+	value, err := fieldValue(entry, p.field)
+	if err != nil {
+		return err
+	}
+
+	p.reloadDatabases()
+
+	fields, ok := p.cache.Get(value)
+	if !ok {
+		generation := p.cacheGeneration()
+		if fields, err = p.lookup(value); err != nil {
+			return err
+		}
+		p.cacheAdd(generation, value, fields)
+	}
+
+	for name, fieldValue := range fields {
+		entry.Fields[p.target+"."+name] = fieldValue
+	}
+	return nil
+}
+
+// reloadDatabases reload changed databases and purge the cache if at least one database was reloaded.
+func (p *geoIPProcessor) reloadDatabases() {
+	reloaded := false
+	for _, db := range p.databases {
+		if db.reloadIfChanged() {
+			reloaded = true
+		}
+	}
+	if !reloaded {
+		return
+	}
+
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+	p.generation++
+	p.cache.Purge()
+}
+
+func (p *geoIPProcessor) cacheGeneration() uint64 {
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+	return p.generation
+}
+
+// cacheAdd cache the result of a lookup, unless databases were reloaded since generation.
+func (p *geoIPProcessor) cacheAdd(generation uint64, value string, fields map[string]string) {
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+	if generation == p.generation {
+		p.cache.Add(value, fields)
+	}
+}
+
+func (p *geoIPProcessor) lookup(value string) (map[string]string, error) {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("field \"%s\" is not a valid ip address", p.field)
+	}
+
+	record := &geoIPRecord{}
+	for _, db := range p.databases {
+		if err := db.lookup(ip, record); err != nil {
+			return nil, fmt.Errorf("unable to lookup ip in %s: %w", db.path, err)
+		}
+	}
+
+	fields := map[string]string{}
+	if record.Country.ISOCode != "" {
+		fields["country_code"] = record.Country.ISOCode
+	}
+	if name := record.Country.Names[geoIPLanguage]; name != "" {
+		fields["country"] = name
+	}
+	if name := record.City.Names[geoIPLanguage]; name != "" {
+		fields["city"] = name
+	}
+	if record.ASN != 0 {
+		fields["asn"] = strconv.FormatUint(uint64(record.ASN), 10)
+	}
+	switch {
+	case record.ASNOrganization != "":
+		fields["organization"] = record.ASNOrganization
+	case record.Organization != "":
+		fields["organization"] = record.Organization
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,267 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"gobana-agent/core"
+)
+
+// mmdbEncoder writes values in the MaxMind DB data section format.
+type mmdbEncoder struct {
+	bytes.Buffer
+}
+
+// control write the type and size of a value, sizes from 29 to 284 use an extra byte.
+func (e *mmdbEncoder) control(dataType, size int) {
+	sizeBits, extraSize := size, -1
+	if size >= 29 {
+		sizeBits, extraSize = 29, size-29
+	}
+	if dataType > 7 {
+		e.WriteByte(byte(sizeBits))
+		e.WriteByte(byte(dataType - 7))
+	} else {
+		e.WriteByte(byte(dataType<<5 | sizeBits))
+	}
+	if extraSize >= 0 {
+		e.WriteByte(byte(extraSize))
+	}
+}
+
+func (e *mmdbEncoder) encode(value interface{}) {
+	switch v := value.(type) {
+	case string:
+		e.control(2, len(v))
+		e.WriteString(v)
+	case uint16:
+		e.control(5, 2)
+		_ = binary.Write(e, binary.BigEndian, v)
+	case uint32:
+		e.control(6, 4)
+		_ = binary.Write(e, binary.BigEndian, v)
+	case uint64:
+		e.control(9, 8)
+		_ = binary.Write(e, binary.BigEndian, v)
+	case []string:
+		e.control(11, len(v))
+		for _, item := range v {
+			e.encode(item)
+		}
+	case map[string]interface{}:
+		e.control(7, len(v))
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			e.encode(key)
+			e.encode(v[key])
+		}
+	}
+}
+
+// buildTestMMDB returns an IPv4 database with a single tree node : addresses of 0.0.0.0/1 get the first record
+// and addresses of 128.0.0.0/1 the second one.
+func buildTestMMDB(low, high map[string]interface{}) []byte {
+	data := &mmdbEncoder{}
+	data.encode(low)
+	highOffset := data.Len()
+	data.encode(high)
+
+	const nodeCount = 1
+	var content bytes.Buffer
+	for _, offset := range []int{0, highOffset} {
+		record := nodeCount + 16 + offset
+		content.Write([]byte{byte(record >> 16), byte(record >> 8), byte(record)})
+	}
+	content.Write(make([]byte, 16))
+	content.Write(data.Bytes())
+
+	metadata := &mmdbEncoder{}
+	metadata.encode(map[string]interface{}{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(1728568536),
+		"database_type":               "Test-City",
+		"description":                 map[string]interface{}{"en": "test database"},
+		"ip_version":                  uint16(4),
+		"languages":                   []string{"en"},
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(24),
+	})
+	content.WriteString("\xab\xcd\xefMaxMind.com")
+	content.Write(metadata.Bytes())
+
+	return content.Bytes()
+}
+
+func testGeoIPRecord(countryCode, country, city string, asn uint32) map[string]interface{} {
+	return map[string]interface{}{
+		"country":                        map[string]interface{}{"iso_code": countryCode, "names": map[string]interface{}{"en": country}},
+		"city":                           map[string]interface{}{"names": map[string]interface{}{"en": city}},
+		"autonomous_system_number":       asn,
+		"autonomous_system_organization": "AS " + country,
+	}
+}
+
+func testGeoIPConfig(path string) *ProcessorConfigStruct {
+	return &ProcessorConfigStruct{Type: processorTypeGeoIP, Field: "client", Files: []string{path}, CacheSize: 10}
+}
+
+func writeTestMMDB(t *testing.T, path string, low, high map[string]interface{}) {
+	t.Helper()
+	if err := os.WriteFile(path, buildTestMMDB(low, high), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGeoIPProcessor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "city.mmdb")
+	writeTestMMDB(t, path, testGeoIPRecord("FR", "France", "Paris", 3215), testGeoIPRecord("JP", "Japan", "Tokyo", 2516))
+
+	p, err := compileGeoIPProcessor(testGeoIPConfig(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		client   string
+		fields   map[string]string
+		hasError bool
+	}{
+		"first network": {
+			client: "81.2.69.160",
+			fields: map[string]string{
+				"geoip.country_code": "FR", "geoip.country": "France", "geoip.city": "Paris",
+				"geoip.asn": "3215", "geoip.organization": "AS France",
+			},
+		},
+		"second network": {
+			client: "202.196.224.1",
+			fields: map[string]string{
+				"geoip.country_code": "JP", "geoip.country": "Japan", "geoip.city": "Tokyo",
+				"geoip.asn": "2516", "geoip.organization": "AS Japan",
+			},
+		},
+		"invalid address": {client: "not-an-ip", fields: map[string]string{}, hasError: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			entry := &core.Entry{Fields: map[string]string{"client": test.client}}
+			err := p.process(entry)
+			if (err != nil) != test.hasError {
+				t.Fatalf("process() error = %v, want error %t", err, test.hasError)
+			}
+			delete(entry.Fields, "client")
+			if !reflect.DeepEqual(entry.Fields, test.fields) {
+				t.Errorf("fields = %v, want %v", entry.Fields, test.fields)
+			}
+		})
+	}
+
+	missing := &ProcessorConfigStruct{Type: processorTypeGeoIP, Field: "client", Files: []string{path + ".missing"}}
+	if _, err := compileGeoIPProcessor(missing); err == nil {
+		t.Errorf("missing database must be rejected")
+	}
+}
+
+func TestGeoIPProcessorReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "city.mmdb")
+	writeTestMMDB(t, path, testGeoIPRecord("FR", "France", "Paris", 3215), testGeoIPRecord("JP", "Japan", "Tokyo", 2516))
+
+	compiled, err := compileGeoIPProcessor(testGeoIPConfig(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := compiled.(*geoIPProcessor)
+	p.databases[0].detector = core.NewFileChangeDetector(path, 0)
+	country := func() string {
+		entry := &core.Entry{Fields: map[string]string{"client": "81.2.69.160"}}
+		if err := p.process(entry); err != nil {
+			t.Fatal(err)
+		}
+		return entry.Fields["geoip.country"]
+	}
+
+	if result := country(); result != "France" {
+		t.Fatalf("country = %q, want France", result)
+	}
+
+	// partially written file is rejected, previous version is kept
+	content := buildTestMMDB(testGeoIPRecord("DE", "Germany", "Berlin", 3320), testGeoIPRecord("JP", "Japan", "Tokyo", 2516))
+	if err := os.WriteFile(path, content[:len(content)/2], 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if result := country(); result != "France" {
+		t.Errorf("country after partial write = %q, want France", result)
+	}
+
+	// file rewritten in place is reloaded and cache is purged
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if result := country(); result != "Germany" {
+		t.Errorf("country after reload = %q, want Germany", result)
+	}
+}
+
+func TestGeoIPProcessorCacheGeneration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "city.mmdb")
+	writeTestMMDB(t, path, testGeoIPRecord("FR", "France", "Paris", 3215), testGeoIPRecord("JP", "Japan", "Tokyo", 2516))
+	compiled, err := compileGeoIPProcessor(testGeoIPConfig(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := compiled.(*geoIPProcessor)
+
+	// a lookup started before a reload must not be cached after the purge
+	generation := p.cacheGeneration()
+	p.databases[0].detector = core.NewFileChangeDetector(path, 0)
+	writeTestMMDB(t, path, testGeoIPRecord("DE", "Germany", "Berlin", 3320), testGeoIPRecord("JP", "Japan", "Tokyo", 2516))
+	p.reloadDatabases()
+	if p.cacheGeneration() == generation {
+		t.Fatalf("reload must start a new cache generation")
+	}
+	p.cacheAdd(generation, "81.2.69.160", map[string]string{"country": "France"})
+	if _, ok := p.cache.Get("81.2.69.160"); ok {
+		t.Errorf("stale lookup must not be cached after reload")
+	}
+
+	// concurrent lookups and reloads
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for _, country := range []string{"France", "Spain", "Italy", "Germany"} {
+			content := buildTestMMDB(testGeoIPRecord("EU", country, "", 3320), testGeoIPRecord("JP", "Japan", "Tokyo", 2516))
+			if err := os.WriteFile(path, content, 0o600); err != nil {
+				t.Error(err)
+				return
+			}
+			p.reloadDatabases()
+		}
+	}()
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				entry := &core.Entry{Fields: map[string]string{"client": "81.2.69.160"}}
+				if err := p.process(entry); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,193 @@
+package agent
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"gobana-agent/core"
+)
+
+const (
+	processorTypeLookup = "lookup"
+
+	lookupFormatCSV      = "csv"
+	lookupFormatYAML     = "yaml"
+	lookupReloadInterval = 5 * time.Second
+)
+
+// lookupTable contains rows indexed by key column.
+type lookupTable map[string]map[string]string
+
+// lookupProcessor join an entry field with a table loaded from a csv or yaml file.
+type lookupProcessor struct {
+	mu       sync.RWMutex
+	field    string
+	target   string
+	file     string
+	format   string
+	key      string
+	columns  []string
+	table    lookupTable
+	detector *core.FileChangeDetector
+}
+
+func compileLookupProcessor(config *ProcessorConfigStruct) (processor, error) {
+	p := &lookupProcessor{
+		field:    config.Field,
+		target:   config.Target,
+		file:     config.File,
+		format:   config.Format,
+		key:      config.Key,
+		columns:  config.Columns,
+		detector: core.NewFileChangeDetector(config.File, lookupReloadInterval),
+	}
+	if p.format == "" {
+		p.format = lookupFormatFromFilename(p.file)
+	}
+
+	var err error
+	if p.table, err = p.load(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func lookupFormatFromFilename(file string) string {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		return lookupFormatYAML
+	default:
+		return lookupFormatCSV
+	}
+}
+
+func (p *lookupProcessor) process(entry *core.Entry) error {
+	value, err := fieldValue(entry, p.field)
+	if err != nil {
+		return err
+	}
+
+	p.reloadIfChanged()
+
+	p.mu.RLock()
+	row, ok := p.table[value]
+	p.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("value \"%s\" of field \"%s\" not found in %s", value, p.field, p.file)
+	}
+
+	for column, columnValue := range row {
+		if column == p.key || (len(p.columns) > 0 && !core.SliceContains(p.columns, column)) {
+			continue
+		}
+		if p.target != "" {
+			column = p.target + "." + column
+		}
+		entry.Fields[column] = columnValue
+	}
+	return nil
+}
+
+func (p *lookupProcessor) reloadIfChanged() {
+	if !p.detector.Changed() {
+		return
+	}
+
+	table, err := p.load()
+	if err != nil {
+		core.Logger.Errorf(processorLogPrefix, "Unable to reload lookup table %s, keep previous version: %s", p.file, err)
+		return
+	}
+
+	p.mu.Lock()
+	p.table = table
+	p.mu.Unlock()
+
+	core.Logger.Infof(processorLogPrefix, "Lookup table %s reloaded (%d rows)", p.file, len(table))
+}
+
+func (p *lookupProcessor) load() (lookupTable, error) {
+	content, err := os.ReadFile(p.file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open lookup table: %w", err)
+	}
+
+	var rows []map[string]string
+	if p.format == lookupFormatYAML {
+		rows, err = decodeLookupYAML(content, p.key)
+	} else {
+		rows, err = decodeLookupCSV(content)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode lookup table %s: %w", p.file, err)
+	}
+
+	table := make(lookupTable, len(rows))
+	for i, row := range rows {
+		key, ok := row[p.key]
+		if !ok {
+			return nil, fmt.Errorf("row #%d of lookup table %s has no column \"%s\"", i, p.file, p.key)
+		}
+		table[key] = row
+	}
+
+	return table, nil
+}
+
+// decodeLookupCSV decode a csv file, first line contains column names.
+func decodeLookupCSV(content []byte) ([]map[string]string, error) {
+	records, err := csv.NewReader(strings.NewReader(string(content))).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := records[0]
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, column := range header {
+			if i < len(record) {
+				row[strings.TrimSpace(column)] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// decodeLookupYAML decode a yaml file containing either a list of rows,
+// or a map of rows indexed by key (key column is then added to each row).
+func decodeLookupYAML(content []byte, key string) ([]map[string]string, error) {
+	var list []map[string]string
+	if err := yaml.Unmarshal(content, &list); err == nil {
+		return list, nil
+	}
+
+	var indexed map[string]map[string]string
+	if err := yaml.Unmarshal(content, &indexed); err != nil {
+		return nil, fmt.Errorf("must contain a list of rows or a map of rows: %w", err)
+	}
+	rows := make([]map[string]string, 0, len(indexed))
+	for _, keyValue := range core.SortedKeys(indexed) {
+		row := indexed[keyValue]
+		if row == nil {
+			row = map[string]string{}
+		}
+		row[key] = keyValue
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,146 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gobana-agent/core"
+)
+
+func writeTestLookupTable(t *testing.T, name, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestLookupProcessor(t *testing.T) {
+	csvFile := writeTestLookupTable(t, "hosts.csv", "host, team,env\nweb-1,shop,prod\ndb-1,data,staging\n")
+	yamlListFile := writeTestLookupTable(t, "hosts.yaml", "- {host: web-1, team: shop, env: prod}\n- {host: db-1, team: data, env: staging}\n")
+	yamlMapFile := writeTestLookupTable(t, "hosts.yml", "web-1: {team: shop, env: prod}\ndb-1: {team: data, env: staging}\n")
+
+	tests := map[string]struct {
+		config   ProcessorConfigStruct
+		host     string
+		fields   map[string]string
+		hasError bool
+	}{
+		"csv": {
+			config: ProcessorConfigStruct{File: csvFile},
+			host:   "web-1",
+			fields: map[string]string{"host": "web-1", "team": "shop", "env": "prod"},
+		},
+		"yaml list": {
+			config: ProcessorConfigStruct{File: yamlListFile},
+			host:   "db-1",
+			fields: map[string]string{"host": "db-1", "team": "data", "env": "staging"},
+		},
+		"yaml map": {
+			config: ProcessorConfigStruct{File: yamlMapFile},
+			host:   "db-1",
+			fields: map[string]string{"host": "db-1", "team": "data", "env": "staging"},
+		},
+		"explicit format": {
+			config: ProcessorConfigStruct{File: yamlListFile, Format: lookupFormatYAML},
+			host:   "web-1",
+			fields: map[string]string{"host": "web-1", "team": "shop", "env": "prod"},
+		},
+		"target": {
+			config: ProcessorConfigStruct{File: csvFile, Target: "inventory"},
+			host:   "web-1",
+			fields: map[string]string{"host": "web-1", "inventory.team": "shop", "inventory.env": "prod"},
+		},
+		"columns": {
+			config: ProcessorConfigStruct{File: csvFile, Columns: []string{"team"}},
+			host:   "web-1",
+			fields: map[string]string{"host": "web-1", "team": "shop"},
+		},
+		"unknown value": {
+			config:   ProcessorConfigStruct{File: csvFile},
+			host:     "cache-1",
+			fields:   map[string]string{"host": "cache-1"},
+			hasError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := test.config
+			config.Type, config.Field, config.Key = processorTypeLookup, "host", "host"
+			p, err := compileLookupProcessor(&config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			entry := &core.Entry{Fields: map[string]string{"host": test.host}}
+			if err := p.process(entry); (err != nil) != test.hasError {
+				t.Fatalf("process() error = %v, want error %t", err, test.hasError)
+			}
+			if !reflect.DeepEqual(entry.Fields, test.fields) {
+				t.Errorf("fields = %v, want %v", entry.Fields, test.fields)
+			}
+		})
+	}
+}
+
+func TestLookupProcessorInvalidTable(t *testing.T) {
+	tests := map[string]struct {
+		name    string
+		content string
+	}{
+		"missing key column": {name: "hosts.csv", content: "name,team\nweb-1,shop\n"},
+		"invalid csv":        {name: "hosts.csv", content: "host,team\nweb-1,shop,prod\n"},
+		"invalid yaml":       {name: "hosts.yaml", content: "web-1: [shop, prod]\n"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			file := writeTestLookupTable(t, test.name, test.content)
+			config := &ProcessorConfigStruct{Type: processorTypeLookup, Field: "host", Key: "host", File: file}
+			if _, err := compileLookupProcessor(config); err == nil {
+				t.Errorf("table %q must be rejected", test.content)
+			}
+		})
+	}
+
+	config := &ProcessorConfigStruct{Type: processorTypeLookup, Field: "host", Key: "host", File: filepath.Join(t.TempDir(), "missing.csv")}
+	if _, err := compileLookupProcessor(config); err == nil {
+		t.Errorf("missing table must be rejected")
+	}
+}
+
+func TestLookupProcessorReload(t *testing.T) {
+	file := writeTestLookupTable(t, "hosts.csv", "host,team\nweb-1,shop\n")
+	compiled, err := compileLookupProcessor(&ProcessorConfigStruct{Type: processorTypeLookup, Field: "host", Key: "host", File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := compiled.(*lookupProcessor)
+	p.detector = core.NewFileChangeDetector(file, 0)
+	team := func() string {
+		entry := &core.Entry{Fields: map[string]string{"host": "web-1"}}
+		if err := p.process(entry); err != nil {
+			t.Fatal(err)
+		}
+		return entry.Fields["team"]
+	}
+
+	// invalid table is rejected, previous version is kept
+	if err := os.WriteFile(file, []byte("name,team\nweb-1,platform\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if result := team(); result != "shop" {
+		t.Errorf("team after invalid update = %q, want shop", result)
+	}
+
+	if err := os.WriteFile(file, []byte("host,team\nweb-1,platform-ops\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if result := team(); result != "platform-ops" {
+		t.Errorf("team after reload = %q, want platform-ops", result)
+	}
+}
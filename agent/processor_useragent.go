@@ -0,0 +1,179 @@
+package agent
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"gobana-agent/core"
+)
+
+const (
+	processorTypeUserAgent = "user_agent"
+
+	userAgentDefaultTarget = "user_agent"
+	userAgentDeviceBot     = "bot"
+	userAgentDeviceOther   = "other"
+)
+
+// Rules used to parse user-agents
+//
+//go:embed resources/user_agents.yaml
+var userAgentRulesFile []byte
+
+type userAgentRule struct {
+	Name    string `yaml:"name"`
+	Pattern string `yaml:"pattern"`
+
+	regex *regexp.Regexp
+}
+
+type userAgentRules struct {
+	Bots     []*userAgentRule `yaml:"bots"`
+	Browsers []*userAgentRule `yaml:"browsers"`
+	OS       []*userAgentRule `yaml:"os"`
+	Devices  []*userAgentRule `yaml:"devices"`
+}
+
+var (
+	loadedUserAgentRules    *userAgentRules
+	loadedUserAgentRulesErr error
+	loadUserAgentRulesOnce  sync.Once
+)
+
+// getUserAgentRules parse and compile embedded rules once.
+func getUserAgentRules() (*userAgentRules, error) {
+	loadUserAgentRulesOnce.Do(func() {
+		rules := &userAgentRules{}
+		if err := yaml.Unmarshal(userAgentRulesFile, rules); err != nil {
+			loadedUserAgentRulesErr = fmt.Errorf("unable to decode user-agent rules: %w", err)
+			return
+		}
+		for _, section := range [][]*userAgentRule{rules.Bots, rules.Browsers, rules.OS, rules.Devices} {
+			for _, rule := range section {
+				regex, err := regexp.Compile(rule.Pattern)
+				if err != nil {
+					loadedUserAgentRulesErr = fmt.Errorf("invalid user-agent rule \"%s\": %w", rule.Name, err)
+					return
+				}
+				rule.regex = regex
+			}
+		}
+		loadedUserAgentRules = rules
+	})
+
+	return loadedUserAgentRules, loadedUserAgentRulesErr
+}
+
+// matchUserAgentRules returns name and version of the first matching rule.
+func matchUserAgentRules(rules []*userAgentRule, userAgent string) (name, version string, ok bool) {
+	for _, rule := range rules {
+		matches := rule.regex.FindStringSubmatch(userAgent)
+		if matches == nil {
+			continue
+		}
+		if i := rule.regex.SubexpIndex("version"); i > 0 {
+			version = strings.ReplaceAll(matches[i], "_", ".")
+		}
+		return rule.Name, version, true
+	}
+
+	return "", "", false
+}
+
+// userAgentProcessor parse a user-agent field into browser, os, device and bot flag.
+type userAgentProcessor struct {
+	field  string
+	target string
+	rules  *userAgentRules
+	cache  *core.LRUCache[string, map[string]string]
+}
+
+func compileUserAgentProcessor(config *ProcessorConfigStruct) (processor, error) {
+	rules, err := getUserAgentRules()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &userAgentProcessor{
+		field:  config.Field,
+		target: config.Target,
+		rules:  rules,
+		cache:  core.NewLRUCache[string, map[string]string](config.CacheSize),
+	}
+	if p.target == "" {
+		p.target = userAgentDefaultTarget
+	}
+
+	return p, nil
+}
+
+func (p *userAgentProcessor) process(entry *core.Entry) error {
+	value, err := fieldValue(entry, p.field)
+	if err != nil {
+		return err
+	}
+
+	fields, ok := p.cache.Get(value)
+	if !ok {
+		fields = p.parse(value)
+		p.cache.Add(value, fields)
+	}
+
+	for name, fieldValue := range fields {
+		entry.Fields[p.target+"."+name] = fieldValue
+	}
+	return nil
+}
+
+func (p *userAgentProcessor) parse(userAgent string) map[string]string {
+	fields := map[string]string{
+		"browser":         "",
+		"browser_version": "",
+		"os":              "",
+		"os_version":      "",
+		"device":          userAgentDeviceOther,
+		"is_bot":          "false",
+	}
+
+	osName, osVersion, _ := matchUserAgentRules(p.rules.OS, userAgent)
+	fields["os"], fields["os_version"] = osName, osVersion
+
+	if botName, botVersion, ok := matchUserAgentRules(p.rules.Bots, userAgent); ok {
+		fields["browser"], fields["browser_version"] = botName, botVersion
+		fields["device"] = userAgentDeviceBot
+		fields["is_bot"] = "true"
+		fields["summary"] = botName
+		return fields
+	}
+
+	if browser, version, ok := matchUserAgentRules(p.rules.Browsers, userAgent); ok {
+		fields["browser"], fields["browser_version"] = browser, version
+	}
+	if device, _, ok := matchUserAgentRules(p.rules.Devices, userAgent); ok {
+		fields["device"] = device
+	}
+	fields["summary"] = userAgentSummary(fields)
+
+	return fields
+}
+
+// userAgentSummary returns a readable summary, e.g. "Chrome 120 on Android".
+func userAgentSummary(fields map[string]string) string {
+	summary := fields["browser"]
+	if summary == "" {
+		summary = "Unknown browser"
+	}
+	if version := strings.Split(fields["browser_version"], ".")[0]; version != "" {
+		summary += " " + version
+	}
+	if fields["os"] != "" {
+		summary += " on " + fields["os"]
+	}
+
+	return summary
+}
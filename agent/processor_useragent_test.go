@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+
+	"gobana-agent/core"
+)
+
+func TestUserAgentProcessor(t *testing.T) {
+	tests := map[string]struct {
+		userAgent string
+		fields    map[string]string
+	}{
+		"chrome on windows": {
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.109 Safari/537.36",
+			fields: map[string]string{
+				"browser": "Chrome", "browser_version": "120.0.6099.109", "os": "Windows", "os_version": "10.0",
+				"device": "desktop", "is_bot": "false", "summary": "Chrome 120 on Windows",
+			},
+		},
+		"edge is not chrome": {
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) " +
+				"Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91",
+			fields: map[string]string{
+				"browser": "Edge", "browser_version": "120.0.2210.91", "os": "Windows", "os_version": "10.0",
+				"device": "desktop", "is_bot": "false", "summary": "Edge 120 on Windows",
+			},
+		},
+		"firefox on linux": {
+			userAgent: "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0",
+			fields: map[string]string{
+				"browser": "Firefox", "browser_version": "121.0", "os": "Linux", "os_version": "",
+				"device": "desktop", "is_bot": "false", "summary": "Firefox 121 on Linux",
+			},
+		},
+		"safari on iphone": {
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) " +
+				"Version/17.1.2 Mobile/15E148 Safari/604.1",
+			fields: map[string]string{
+				"browser": "Safari", "browser_version": "17.1.2", "os": "iOS", "os_version": "17.1.2",
+				"device": "mobile", "is_bot": "false", "summary": "Safari 17 on iOS",
+			},
+		},
+		"android tablet": {
+			userAgent: "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			fields: map[string]string{
+				"browser": "Chrome", "browser_version": "120.0.0.0", "os": "Android", "os_version": "13",
+				"device": "tablet", "is_bot": "false", "summary": "Chrome 120 on Android",
+			},
+		},
+		"search engine bot": {
+			userAgent: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			fields: map[string]string{
+				"browser": "Googlebot", "browser_version": "2.1", "os": "", "os_version": "",
+				"device": "bot", "is_bot": "true", "summary": "Googlebot",
+			},
+		},
+		"http library": {
+			userAgent: "curl/8.4.0",
+			fields: map[string]string{
+				"browser": "curl", "browser_version": "8.4.0", "os": "", "os_version": "",
+				"device": "bot", "is_bot": "true", "summary": "curl",
+			},
+		},
+		"generic crawler": {
+			userAgent: "Mozilla/5.0 (compatible; ExampleCrawler; +https://example.com)",
+			fields: map[string]string{
+				"browser": "Bot", "browser_version": "", "os": "", "os_version": "",
+				"device": "bot", "is_bot": "true", "summary": "Bot",
+			},
+		},
+		"unknown": {
+			userAgent: "-",
+			fields: map[string]string{
+				"browser": "", "browser_version": "", "os": "", "os_version": "",
+				"device": "other", "is_bot": "false", "summary": "Unknown browser",
+			},
+		},
+	}
+
+	p, err := compileUserAgentProcessor(&ProcessorConfigStruct{Type: processorTypeUserAgent, Field: "agent", Target: "ua", CacheSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			expected := map[string]string{"agent": test.userAgent}
+			for field, value := range test.fields {
+				expected["ua."+field] = value
+			}
+
+			// second pass is read from cache
+			for i := 0; i < 2; i++ {
+				entry := &core.Entry{Fields: map[string]string{"agent": test.userAgent}}
+				if err := p.process(entry); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(entry.Fields, expected) {
+					t.Errorf("fields = %v, want %v", entry.Fields, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestUserAgentProcessorMissingField(t *testing.T) {
+	p, err := compileUserAgentProcessor(&ProcessorConfigStruct{Type: processorTypeUserAgent, Field: "agent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.process(&core.Entry{Fields: map[string]string{}}); err == nil {
+		t.Errorf("missing field must be rejected")
+	}
+}
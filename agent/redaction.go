@@ -0,0 +1,218 @@
+package agent
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gobana-agent/core"
+)
+
+const (
+	redactionModeMask = "mask"
+	redactionModeDrop = "drop"
+	redactionModeHash = "hash"
+
+	redactionHashLength = 16
+)
+
+// redactionDetector find sensitive values in a text.
+// If group is set, only this capture group of the match is redacted (e.g. the token of a bearer header).
+type redactionDetector struct {
+	name     string
+	regex    *regexp.Regexp
+	group    int
+	validate func(value string) bool
+}
+
+// panIssuerPrefixRegex match major industry identifiers (first digit) used by card networks.
+var panIssuerPrefixRegex = regexp.MustCompile(`^[2-6]`)
+
+// builtinRedactionDetectors are ordered : tokens are searched before emails, cards and ip addresses.
+var builtinRedactionDetectors = []*redactionDetector{
+	{
+		name:  "jwt",
+		regex: regexp.MustCompile(`eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]*`),
+	},
+	{
+		name:  "bearer",
+		regex: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)`),
+		group: 1,
+	},
+	{
+		name:  "email",
+		regex: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	},
+	{
+		name:     "pan",
+		regex:    regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`),
+		validate: isValidPAN,
+	},
+	{
+		name:     "ipv6",
+		regex:    regexp.MustCompile(`(?i)[0-9a-f]*:[0-9a-f:]*:[0-9a-f:.]*[0-9a-f]`),
+		validate: isValidIPv6,
+	},
+	{
+		name:  "ipv4",
+		regex: regexp.MustCompile(`\b(?:25[0-5]|2[0-4]\d|1?\d?\d)(?:\.(?:25[0-5]|2[0-4]\d|1?\d?\d)){3}\b`),
+	},
+}
+
+// redactor replace sensitive values of fields and raw content.
+type redactor struct {
+	detectors []*redactionDetector
+	mode      string
+	hashKey   []byte
+}
+
+func compileRedactor(config *RedactionConfigStruct) (*redactor, error) {
+	r := &redactor{
+		mode:    config.Mode,
+		hashKey: []byte(config.HashKey),
+	}
+
+	// keep built-in order whatever the configuration order
+	for _, detector := range builtinRedactionDetectors {
+		if core.SliceContains(config.Detectors, detector.name) {
+			r.detectors = append(r.detectors, detector)
+		}
+	}
+	for i, custom := range config.Custom {
+		regex, err := regexp.Compile(custom.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("custom[%d].pattern is invalid: %w", i, err)
+		}
+		r.detectors = append(r.detectors, &redactionDetector{name: custom.Name, regex: regex})
+	}
+
+	return r, nil
+}
+
+func (r *redactor) enabled() bool {
+	return r != nil && len(r.detectors) > 0
+}
+
+// redact returns a redacted copy of fields and raw content.
+func (r *redactor) redact(fields map[string]string, raw string) (map[string]string, string) {
+	redactedFields := make(map[string]string, len(fields))
+	for name, value := range fields {
+		redacted, found := r.redactText(value)
+		if found && r.mode == redactionModeDrop {
+			continue
+		}
+		redactedFields[name] = redacted
+	}
+	redactedRaw, _ := r.redactText(raw)
+
+	return redactedFields, redactedRaw
+}
+
+// redactionSpan is a sensitive value of a text and its replacement.
+type redactionSpan struct {
+	start       int
+	end         int
+	replacement string
+}
+
+// redactText replace sensitive values of text, it returns true if at least one value was found.
+// Detectors are applied in order on the original text and a value is only redacted once : a value
+// found by a detector is ignored by next ones (e.g. the token of "Bearer <jwt>" is redacted as a jwt).
+func (r *redactor) redactText(text string) (string, bool) {
+	var spans []redactionSpan
+	for _, detector := range r.detectors {
+		for _, match := range detector.regex.FindAllStringSubmatchIndex(text, -1) {
+			start, end := match[0], match[1]
+			if detector.group > 0 {
+				if len(match) <= detector.group*2+1 || match[detector.group*2] < 0 {
+					continue
+				}
+				start, end = match[detector.group*2], match[detector.group*2+1]
+			}
+			value := text[start:end]
+			if detector.validate != nil && !detector.validate(value) {
+				continue
+			}
+			if overlapsRedactionSpans(spans, start, end) {
+				continue
+			}
+			spans = append(spans, redactionSpan{start: start, end: end, replacement: r.replacement(detector.name, value)})
+		}
+	}
+	if len(spans) == 0 {
+		return text, false
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	var builder strings.Builder
+	previous := 0
+	for _, span := range spans {
+		builder.WriteString(text[previous:span.start])
+		builder.WriteString(span.replacement)
+		previous = span.end
+	}
+	builder.WriteString(text[previous:])
+
+	return builder.String(), true
+}
+
+func overlapsRedactionSpans(spans []redactionSpan, start, end int) bool {
+	for _, span := range spans {
+		if start < span.end && span.start < end {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *redactor) replacement(detectorName, value string) string {
+	switch r.mode {
+	case redactionModeDrop:
+		return ""
+	case redactionModeHash:
+		mac := hmac.New(sha256.New, r.hashKey)
+		mac.Write([]byte(value))
+		return fmt.Sprintf("%s:%s", detectorName, hex.EncodeToString(mac.Sum(nil))[:redactionHashLength])
+	default:
+		return fmt.Sprintf("[REDACTED:%s]", detectorName)
+	}
+}
+
+// isValidPAN check card number using Luhn algorithm. Numbers without separators must start with
+// an issuer prefix, so other long numbers (e.g. timestamps in milliseconds) are not redacted.
+func isValidPAN(value string) bool {
+	sum := 0
+	digits := 0
+	double := false
+	for i := len(value) - 1; i >= 0; i-- {
+		c := value[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+		double = !double
+	}
+	if digits < 13 || digits > 19 || sum%10 != 0 {
+		return false
+	}
+
+	return digits != len(value) || panIssuerPrefixRegex.MatchString(value)
+}
+
+func isValidIPv6(value string) bool {
+	ip := net.ParseIP(value)
+	return ip != nil && ip.To4() == nil
+}
@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gobana-agent/core"
+)
+
+const (
+	fieldNameSeverity = "_severity"
+	// severityUnknown is the severity of entries without level field or with an unrecognised level
+	severityUnknown = "unknown"
+
+	triggerTypeSeverityGreaterOrEqual = "severity_gte"
+	triggerTypeSeverityLowerOrEqual   = "severity_lte"
+)
+
+// severityLevels contains normalised severities, from the lowest to the highest.
+var severityLevels = []string{"trace", "debug", "info", "notice", "warning", "error", "critical", "alert", "emergency"}
+
+// severityAliases map common level names (lower case) to normalised severities.
+var severityAliases = map[string]string{
+	"trace": "trace", "trc": "trace", "t": "trace", "verbose": "trace", "finest": "trace", "finer": "trace",
+	"debug": "debug", "dbg": "debug", "d": "debug", "fine": "debug",
+	"debug1": "debug", "debug2": "debug", "debug3": "debug", "debug4": "debug", "debug5": "debug",
+	"info": "info", "inf": "info", "i": "info", "information": "info", "informational": "info", "log": "info",
+	"notice": "notice", "n": "notice",
+	"warning": "warning", "warn": "warning", "wrn": "warning", "w": "warning",
+	"error": "error", "err": "error", "e": "error", "severe": "error",
+	"critical": "critical", "crit": "critical", "c": "critical", "fatal": "critical", "f": "critical",
+	"alert": "alert", "a": "alert",
+	"emergency": "emergency", "emerg": "emergency", "panic": "emergency",
+}
+
+// syslogSeverities map syslog numeric severities (0 to 7).
+var syslogSeverities = []string{"emergency", "alert", "critical", "error", "warning", "notice", "info", "debug"}
+
+// bunyanSeverities map bunyan / pino numeric levels.
+var bunyanSeverities = map[int]string{10: "trace", 20: "debug", 30: "info", 40: "warning", 50: "error", 60: "critical"}
+
+// defaultSeverityFields are fields used to compute severity when no field is configured.
+var defaultSeverityFields = []string{"level", "severity", "level_name", "loglevel", "priority"}
+
+// normalizeSeverity returns the normalised severity of a level value.
+// Mapping (lower case keys) has priority over built-in names and numeric levels.
+func normalizeSeverity(value string, mapping map[string]string) (string, bool) {
+	lowerValue := strings.ToLower(strings.TrimSpace(value))
+	if severity, ok := mapping[lowerValue]; ok {
+		return severity, true
+	}
+	if severity, ok := severityAliases[lowerValue]; ok {
+		return severity, true
+	}
+
+	number, err := strconv.Atoi(lowerValue)
+	if err != nil {
+		return "", false
+	}
+	switch {
+	case number >= 0 && number < len(syslogSeverities):
+		return syslogSeverities[number], true
+	case number >= 10:
+		// bunyan levels are rounded down, e.g. 35 is between info and warning
+		level := number / 10 * 10
+		if level > 60 {
+			level = 60
+		}
+		return bunyanSeverities[level], true
+	default:
+		return "", false
+	}
+}
+
+// severityRank returns the position of a severity in severityLevels, -1 if unknown.
+func severityRank(severity string) int {
+	for i, level := range severityLevels {
+		if level == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// applySeverity set the "_severity" field of an entry from its level field, "unknown" if it has no known level.
+func applySeverity(parser *ParserConfigStruct, entry *core.Entry) {
+	fields := defaultSeverityFields
+	if parser.Severity.Field != "" {
+		fields = []string{parser.Severity.Field}
+	}
+
+	entry.Fields[fieldNameSeverity] = severityUnknown
+	for _, field := range fields {
+		value, ok := entry.Fields[field]
+		if !ok || value == "" {
+			continue
+		}
+		if severity, ok := normalizeSeverity(value, parser.Severity.mapping); ok {
+			entry.Fields[fieldNameSeverity] = severity
+		}
+		return
+	}
+}
+
+// compileSeverityTest returns a comparison of severity of field values with severity operand.
+func compileSeverityTest(operator, operand string) (func(value string) bool, error) {
+	operandSeverity, ok := normalizeSeverity(operand, nil)
+	if !ok {
+		return nil, fmt.Errorf("\"%s\" is not a severity (%s)", operand, strings.Join(severityLevels, ", "))
+	}
+	operandRank := severityRank(operandSeverity)
+
+	return func(value string) bool {
+		severity, ok := normalizeSeverity(value, nil)
+		if !ok {
+			return false
+		}
+		if operator == triggerTypeSeverityGreaterOrEqual {
+			return severityRank(severity) >= operandRank
+		}
+		return severityRank(severity) <= operandRank
+	}, nil
+}
@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"testing"
+
+	"gobana-agent/core"
+)
+
+func TestNormalizeSeverity(t *testing.T) {
+	mapping := map[string]string{"extreme": "critical", "warn": "error"}
+	tests := map[string]struct {
+		value    string
+		severity string
+		ok       bool
+	}{
+		"name":                     {value: "warning", severity: "warning", ok: true},
+		"upper case":               {value: "CRITICAL", severity: "critical", ok: true},
+		"alias":                    {value: "fatal", severity: "critical", ok: true},
+		"short alias":              {value: " E ", severity: "error", ok: true},
+		"postgresql debug level":   {value: "DEBUG3", severity: "debug", ok: true},
+		"syslog level":             {value: "3", severity: "error", ok: true},
+		"syslog emergency":         {value: "0", severity: "emergency", ok: true},
+		"bunyan level":             {value: "30", severity: "info", ok: true},
+		"bunyan level rounded":     {value: "45", severity: "warning", ok: true},
+		"bunyan level above fatal": {value: "70", severity: "critical", ok: true},
+		"mapping":                  {value: "Extreme", severity: "critical", ok: true},
+		"mapping overrides alias":  {value: "WARN", severity: "error", ok: true},
+		"unknown name":             {value: "loud", ok: false},
+		"unknown number":           {value: "8", ok: false},
+		"negative number":          {value: "-1", ok: false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			severity, ok := normalizeSeverity(test.value, mapping)
+			if severity != test.severity || ok != test.ok {
+				t.Errorf("normalizeSeverity(%q) = %q, %t, want %q, %t", test.value, severity, ok, test.severity, test.ok)
+			}
+		})
+	}
+}
+
+func TestApplySeverity(t *testing.T) {
+	tests := map[string]struct {
+		config   SeverityConfigStruct
+		fields   map[string]string
+		severity string
+	}{
+		"default field":          {fields: map[string]string{"level": "WARN"}, severity: "warning"},
+		"first default field":    {fields: map[string]string{"priority": "3", "severity": "info"}, severity: "info"},
+		"empty field is skipped": {fields: map[string]string{"level": "", "loglevel": "debug"}, severity: "debug"},
+		"configured field": {
+			config: SeverityConfigStruct{Field: "lvl"}, fields: map[string]string{"lvl": "err", "level": "info"}, severity: "error",
+		},
+		"mapping": {
+			config: SeverityConfigStruct{Mapping: map[string]string{"#": "warning"}}, fields: map[string]string{"level": "#"}, severity: "warning",
+		},
+		"no level field": {fields: map[string]string{"message": "GET /"}, severity: severityUnknown},
+		"configured field not present": {
+			config: SeverityConfigStruct{Field: "lvl"}, fields: map[string]string{"level": "info"}, severity: severityUnknown,
+		},
+		"unrecognised level": {fields: map[string]string{"level": "loud"}, severity: severityUnknown},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			parser := &ParserConfigStruct{Severity: test.config}
+			parser.Severity.compile()
+			entry := &core.Entry{Fields: test.fields}
+			applySeverity(parser, entry)
+			if severity := entry.Fields[fieldNameSeverity]; severity != test.severity {
+				t.Errorf("severity = %q, want %q", severity, test.severity)
+			}
+		})
+	}
+}
+
+func TestSeverityTest(t *testing.T) {
+	gte, err := compileSeverityTest(triggerTypeSeverityGreaterOrEqual, "error")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lte, err := compileSeverityTest(triggerTypeSeverityLowerOrEqual, "info")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		value string
+		gte   bool
+		lte   bool
+	}{
+		"debug":    {value: "debug", gte: false, lte: true},
+		"info":     {value: "info", gte: false, lte: true},
+		"warning":  {value: "warning", gte: false, lte: false},
+		"error":    {value: "error", gte: true, lte: false},
+		"critical": {value: "critical", gte: true, lte: false},
+		"unknown":  {value: severityUnknown, gte: false, lte: false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if result := gte(test.value); result != test.gte {
+				t.Errorf("severity_gte error(%q) = %t, want %t", test.value, result, test.gte)
+			}
+			if result := lte(test.value); result != test.lte {
+				t.Errorf("severity_lte info(%q) = %t, want %t", test.value, result, test.lte)
+			}
+		})
+	}
+
+	if _, err := compileSeverityTest(triggerTypeSeverityGreaterOrEqual, "loud"); err == nil {
+		t.Errorf("unknown severity operand must be rejected")
+	}
+}
@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gobana-agent/core"
+)
+
+const (
+	subParserModeJSON     = "json"
+	subParserModeLogfmt   = "logfmt"
+	subParserModeKeyValue = "kv"
+	subParserModeRegex    = "regex"
+
+	logfmtPairSeparator     = " "
+	logfmtKeyValueSeparator = "="
+)
+
+// applySubParsers parse captured fields (e.g. a json body or a logfmt context) and merge
+// resulting fields into entry, prefixed by sub-parser prefix.
+func applySubParsers(parser *ParserConfigStruct, entry *core.Entry) {
+	for i, subParser := range parser.SubParsers {
+		value, ok := entry.Fields[subParser.Field]
+		if !ok || value == "" {
+			continue
+		}
+
+		fields, err := subParser.parse(value, parser.jsonFlattenLimits())
+		if err != nil {
+			core.Logger.Debugf(watcherLogPrefix, "Sub-parser #%d of parser \"%s\" failed: %s", i, parser.Name, err)
+			continue
+		}
+		for name, fieldValue := range fields {
+			if subParser.Prefix != "" {
+				name = subParser.Prefix + "." + name
+			}
+			entry.Fields[name] = fieldValue
+		}
+	}
+}
+
+func (s *SubParserConfigStruct) parse(value string, limits core.JSONFlattenLimits) (map[string]string, error) {
+	fields := map[string]string{}
+
+	switch s.Mode {
+	case subParserModeJSON:
+		var jsonData interface{}
+		if err := json.Unmarshal([]byte(value), &jsonData); err != nil {
+			return nil, fmt.Errorf("unable to parse field \"%s\" as json: %w", s.Field, err)
+		}
+		// without mapping, all fields are captured
+		extractJSONFields(fields, jsonData, s.jsonSelectors, len(s.jsonSelectors) == 0, limits)
+	case subParserModeLogfmt:
+		fields = core.ParseKeyValues(value, logfmtPairSeparator, logfmtKeyValueSeparator)
+	case subParserModeKeyValue:
+		fields = core.ParseKeyValues(value, s.PairSeparator, s.KeyValueSeparator)
+	case subParserModeRegex:
+		matches := s.regex.FindStringSubmatch(value)
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("field \"%s\" not match regex", s.Field)
+		}
+		for i, name := range s.regex.SubexpNames() {
+			if i == 0 || name == "" {
+				continue
+			}
+			fields[name] = matches[i]
+		}
+	default:
+		return nil, fmt.Errorf("unknown mode %s", s.Mode)
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+
+	"gobana-agent/core"
+)
+
+func TestApplySubParsers(t *testing.T) {
+	config := mustReadTestConfig(t, `
+application: test
+smtp:
+  from_email: "gobana@example.com"
+parsers:
+  - name: app
+    mode: regex
+    regex_pattern: '^(?P<message>.*)$'
+    files_included: ["/var/log/app.log"]
+    json_capture_max_keys: 3
+    sub_parsers:
+      - {field: context, mode: json, prefix: context}
+      - {field: body, mode: json, json_fields: {order: "order.id", items: "items[*].sku"}}
+      - {field: extra, mode: logfmt}
+      - {field: tags, mode: kv, pair_separator: ";", key_value_separator: ":", prefix: tags}
+      - {field: request, mode: regex, regex_pattern: '^(?P<method>[A-Z]+) (?P<path>\S+)$', prefix: request}
+`)
+	parser := config.Parsers[0]
+
+	tests := map[string]struct {
+		fields   map[string]string
+		expected map[string]string
+	}{
+		"json captured with prefix": {
+			fields:   map[string]string{"context": `{"user": {"id": 42}, "roles": ["admin"]}`},
+			expected: map[string]string{"context.user.id": "42", "context.roles.0": "admin"},
+		},
+		"json keys limit": {
+			fields:   map[string]string{"context": `{"a": 1, "b": 2, "c": 3, "d": 4}`},
+			expected: map[string]string{"context.a": "1", "context.b": "2", "context.c": "3"},
+		},
+		"json mapping": {
+			fields:   map[string]string{"body": `{"order": {"id": 5, "total": 10}, "items": [{"sku": "A1"}, {"sku": "B2"}]}`},
+			expected: map[string]string{"order": "5", "items.0": "A1", "items.1": "B2"},
+		},
+		"logfmt without prefix": {
+			fields:   map[string]string{"extra": `user=bob msg="hello world"`},
+			expected: map[string]string{"user": "bob", "msg": "hello world"},
+		},
+		"kv with separators": {
+			fields:   map[string]string{"tags": `env:prod;team:shop`},
+			expected: map[string]string{"tags.env": "prod", "tags.team": "shop"},
+		},
+		"regex": {
+			fields:   map[string]string{"request": "GET /orders"},
+			expected: map[string]string{"request.method": "GET", "request.path": "/orders"},
+		},
+		"regex not matching": {
+			fields:   map[string]string{"request": "not a request"},
+			expected: map[string]string{},
+		},
+		"invalid json": {
+			fields:   map[string]string{"context": `{"user": `},
+			expected: map[string]string{},
+		},
+		"empty field": {
+			fields:   map[string]string{"extra": ""},
+			expected: map[string]string{},
+		},
+		"sub-parsed field overrides captured field": {
+			fields:   map[string]string{"extra": "user=bob", "user": "alice"},
+			expected: map[string]string{"user": "bob"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			entry := &core.Entry{Fields: map[string]string{}}
+			for field, value := range test.fields {
+				entry.Fields[field] = value
+			}
+			applySubParsers(parser, entry)
+
+			// source fields are kept
+			expected := map[string]string{}
+			for field, value := range test.fields {
+				expected[field] = value
+			}
+			for field, value := range test.expected {
+				expected[field] = value
+			}
+			if !reflect.DeepEqual(entry.Fields, expected) {
+				t.Errorf("fields = %v, want %v", entry.Fields, expected)
+			}
+		})
+	}
+}
+
+func TestSubParserInvalidConfig(t *testing.T) {
+	_, err := readTestConfig(t, `
+application: test
+smtp:
+  from_email: "gobana@example.com"
+parsers:
+  - name: app
+    mode: regex
+    regex_pattern: '^(?P<message>.*)$'
+    files_included: ["/var/log/app.log"]
+    sub_parsers:
+      - {field: request, mode: regex, regex_pattern: '^(?P<method>[A-Z+ $'}
+`)
+	if err == nil {
+		t.Fatalf("invalid sub-parser regex must be rejected")
+	}
+}
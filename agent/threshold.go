@@ -0,0 +1,142 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"gobana-agent/core"
+)
+
+// thresholdCounter count entries matching a trigger in a sliding window, for each group of "group_by" values.
+type thresholdCounter struct {
+	mu     sync.Mutex
+	config *TriggerThresholdConfigStruct
+	groups map[string]*thresholdGroup
+}
+
+type thresholdGroup struct {
+	// match dates within window, oldest first (at most count)
+	hits []time.Time
+	// latest matching entries, sent with the alert
+	samples []*Alert
+	// alert already sent, until count falls under threshold
+	fired bool
+}
+
+func newThresholdCounter(config *TriggerThresholdConfigStruct) *thresholdCounter {
+	return &thresholdCounter{config: config, groups: map[string]*thresholdGroup{}}
+}
+
+// add count a matching entry. It returns an alert when the threshold is crossed, nil otherwise.
+// newAlert is only called for entries kept as samples or alerted.
+func (counter *thresholdCounter) add(entry *core.Entry, now time.Time, newAlert func() *Alert) *Alert {
+	key := triggerGroupKey(entry, counter.config.GroupBy)
+
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+
+	state, ok := counter.groups[key]
+	if !ok {
+		state = &thresholdGroup{}
+		counter.groups[key] = state
+	}
+	state.prune(now.Add(-counter.config.Window), counter.config.Count)
+	// only the latest count hits are needed to know if the threshold is reached
+	if len(state.hits) >= counter.config.Count {
+		state.hits = state.hits[1:]
+	}
+	state.hits = append(state.hits, now)
+
+	if state.fired {
+		return nil
+	}
+	if len(state.hits) < counter.config.Count {
+		if counter.config.Samples > 0 {
+			if len(state.samples) >= counter.config.Samples {
+				state.samples = state.samples[1:]
+			}
+			state.samples = append(state.samples, newAlert())
+		}
+		return nil
+	}
+
+	state.fired = true
+	alert := newAlert()
+	alert.Count = len(state.hits)
+	alert.Window = counter.config.Window
+	alert.Group = triggerGroupDescription(alert, counter.config.GroupBy)
+	alert.Samples = state.samples
+	state.samples = nil
+	return alert
+}
+
+// prune remove groups without match within window.
+func (counter *thresholdCounter) prune(now time.Time) {
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+
+	for key, state := range counter.groups {
+		state.prune(now.Add(-counter.config.Window), counter.config.Count)
+		if len(state.hits) == 0 {
+			delete(counter.groups, key)
+		}
+	}
+}
+
+// prune remove hits older than since, the group can fire again once its count falls under threshold.
+func (state *thresholdGroup) prune(since time.Time, count int) {
+	expired := 0
+	for expired < len(state.hits) && state.hits[expired].Before(since) {
+		expired++
+	}
+	if expired > 0 {
+		state.hits = append(state.hits[:0], state.hits[expired:]...)
+	}
+	if len(state.hits) < count {
+		state.fired = false
+	}
+	if len(state.hits) == 0 {
+		state.samples = nil
+	}
+}
+
+// triggerGroupKey returns the key of the group of entry.
+func triggerGroupKey(entry *core.Entry, groupBy []string) string {
+	if len(groupBy) == 0 {
+		return ""
+	}
+
+	values := make([]string, len(groupBy))
+	for i, field := range groupBy {
+		values[i], _ = entryFieldValue(entry, field)
+	}
+	return strings.Join(values, "\x00")
+}
+
+// triggerGroupDescription describe the group of an alert, e.g. "host=web1, status=502".
+// Values are only read from the alert, whose fields are redacted : values dropped by redaction are empty.
+func triggerGroupDescription(alert *Alert, groupBy []string) string {
+	descriptions := make([]string, len(groupBy))
+	for i, field := range groupBy {
+		descriptions[i] = fmt.Sprintf("%s=%s", field, alertFieldValue(alert, field))
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// alertFieldValue returns the value of a field of alert, or of a special field (see entryFieldValue).
+func alertFieldValue(alert *Alert, name string) string {
+	switch name {
+	case "_parser":
+		return alert.ParserName
+	case "_filename":
+		return alert.Filename
+	case "_application":
+		return alert.Application
+	case "_server":
+		return alert.Server
+	default:
+		return alert.Fields[name]
+	}
+}
@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"gobana-agent/core"
+)
+
+func TestThresholdCounter(t *testing.T) {
+	counter := newThresholdCounter(&TriggerThresholdConfigStruct{Count: 3, Window: time.Minute, GroupBy: []string{"host"}, Samples: 2})
+	entry := &core.Entry{Fields: map[string]string{"host": "web1"}}
+	newTestAlert := func() *Alert { return newAlert(entry, "errors") }
+	start := time.Date(2024, 10, 10, 13, 55, 0, 0, time.UTC)
+
+	for i := 0; i < 2; i++ {
+		if alert := counter.add(entry, start.Add(time.Duration(i)*time.Second), newTestAlert); alert != nil {
+			t.Fatalf("alert sent before threshold at hit %d", i+1)
+		}
+	}
+	alert := counter.add(entry, start.Add(2*time.Second), newTestAlert)
+	if alert == nil {
+		t.Fatalf("alert must be sent when threshold is reached")
+	}
+	if alert.Count != 3 || alert.Group != "host=web1" || len(alert.Samples) != 2 {
+		t.Errorf("alert count = %d, group = %q, samples = %d, want 3, \"host=web1\", 2", alert.Count, alert.Group, len(alert.Samples))
+	}
+
+	// while fired, hits are capped to count
+	for i := 3; i < 1000; i++ {
+		if alert := counter.add(entry, start.Add(time.Duration(i)*time.Millisecond+2*time.Second), newTestAlert); alert != nil {
+			t.Fatalf("alert sent twice at hit %d", i+1)
+		}
+	}
+	if hits := len(counter.groups["web1"].hits); hits != 3 {
+		t.Errorf("hits of fired group = %d, want 3", hits)
+	}
+
+	// other groups are counted separately
+	other := &core.Entry{Fields: map[string]string{"host": "web2"}}
+	if alert := counter.add(other, start.Add(3*time.Second), func() *Alert { return newAlert(other, "errors") }); alert != nil {
+		t.Errorf("alert sent for another group")
+	}
+
+	// threshold is crossed again once count fell under threshold within window
+	later := start.Add(10 * time.Minute)
+	for i := 0; i < 3; i++ {
+		alert = counter.add(entry, later.Add(time.Duration(i)*time.Second), newTestAlert)
+	}
+	if alert == nil || alert.Count != 3 {
+		t.Errorf("alert must be sent again after window, got %v", alert)
+	}
+
+	counter.prune(later.Add(time.Hour))
+	if len(counter.groups) != 0 {
+		t.Errorf("groups without recent hit must be pruned, got %d", len(counter.groups))
+	}
+}
+
+// setTestRedaction enable redaction of emails with mode until the end of the test.
+func setTestRedaction(t *testing.T, mode string) {
+	t.Helper()
+	redactor, err := compileRedactor(&RedactionConfigStruct{Detectors: []string{"email"}, Mode: mode})
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := AppConfig.Redaction.redactor
+	AppConfig.Redaction.redactor = redactor
+	t.Cleanup(func() { AppConfig.Redaction.redactor = previous })
+}
+
+func TestThresholdCounterRedactedGroup(t *testing.T) {
+	tests := map[string]struct {
+		mode  string
+		group string
+	}{
+		"mask": {mode: redactionModeMask, group: "user=[REDACTED:email], _parser=app"},
+		"drop": {mode: redactionModeDrop, group: "user=, _parser=app"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			setTestRedaction(t, test.mode)
+			counter := newThresholdCounter(&TriggerThresholdConfigStruct{Count: 1, Window: time.Minute, GroupBy: []string{"user", "_parser"}})
+			entry := &core.Entry{Metadata: core.EntryMetadata{Parser: "app"}, Fields: map[string]string{"user": "bob@example.com"}}
+
+			alert := counter.add(entry, time.Now(), func() *Alert { return newAlert(entry, "logins") })
+			if alert == nil {
+				t.Fatalf("alert must be sent when threshold is reached")
+			}
+			if alert.Group != test.group {
+				t.Errorf("group = %q, want %q", alert.Group, test.group)
+			}
+		})
+	}
+}
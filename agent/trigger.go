@@ -0,0 +1,477 @@
+package agent
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"gobana-agent/core"
+)
+
+const (
+	triggerTypeRegex        = "match_regex"
+	triggerTypeRegexAlias   = "regex"
+	triggerTypeEqual        = "is"
+	triggerTypeNotEqual     = "is_not"
+	triggerTypeContains     = "contains"
+	triggerTypeNotContains  = "not_contains"
+	triggerTypeStartWith    = "start_with"
+	triggerTypeNotStartWith = "not_start_with"
+
+	triggerTypeGreaterThan    = "gt"
+	triggerTypeGreaterOrEqual = "gte"
+	triggerTypeLowerThan      = "lt"
+	triggerTypeLowerOrEqual   = "lte"
+	triggerTypeBetween        = "between"
+	triggerTypeNumberEqual    = "eq_num"
+	triggerTypeIn             = "in"
+	triggerTypeNotIn          = "not_in"
+	triggerTypeExists         = "exists"
+	triggerTypeNotExists      = "not_exists"
+	triggerTypeCIDR           = "cidr"
+	triggerTypeGlob           = "glob"
+
+	// separator of values in a scalar value, e.g. "500,599" for "between" or "500,502,503" for "in"
+	triggerListSeparator = ","
+)
+
+// triggerListOperators accept a list of values
+var triggerListOperators = []string{triggerTypeIn, triggerTypeNotIn, triggerTypeCIDR, triggerTypeGlob, triggerTypeBetween}
+
+// triggerCondition is a condition compiled when config is loaded, it is immutable and safe for concurrent use.
+type triggerCondition interface {
+	match(entry *core.Entry) bool
+}
+
+// triggerAllCondition is valid when all conditions are valid, evaluation stops at the first invalid condition.
+type triggerAllCondition []triggerCondition
+
+func (conditions triggerAllCondition) match(entry *core.Entry) bool {
+	for _, condition := range conditions {
+		if !condition.match(entry) {
+			return false
+		}
+	}
+	return true
+}
+
+// triggerAnyCondition is valid when one condition is valid, evaluation stops at the first valid condition.
+type triggerAnyCondition []triggerCondition
+
+func (conditions triggerAnyCondition) match(entry *core.Entry) bool {
+	for _, condition := range conditions {
+		if condition.match(entry) {
+			return true
+		}
+	}
+	return false
+}
+
+type triggerNotCondition struct {
+	condition triggerCondition
+}
+
+func (c *triggerNotCondition) match(entry *core.Entry) bool {
+	return !c.condition.match(entry)
+}
+
+type triggerExistsCondition struct {
+	field  string
+	exists bool
+}
+
+func (c *triggerExistsCondition) match(entry *core.Entry) bool {
+	_, exists := entryFieldValue(entry, c.field)
+	return exists == c.exists
+}
+
+// triggerFieldCondition test the value of a field. Missing fields and empty values never match.
+type triggerFieldCondition struct {
+	field string
+	test  func(value string) bool
+}
+
+func (c *triggerFieldCondition) match(entry *core.Entry) bool {
+	value, exists := entryFieldValue(entry, c.field)
+	if !exists || value == "" {
+		return false
+	}
+	return c.test(value)
+}
+
+// entryFieldValue returns the value of a field of entry, or of a special field : "_parser", "_filename",
+// "_application" or "_server".
+func entryFieldValue(entry *core.Entry, name string) (string, bool) {
+	switch name {
+	case "_parser":
+		return entry.Metadata.Parser, true
+	case "_filename":
+		return entry.Metadata.Filename, true
+	case "_application":
+		return entry.Metadata.Application, true
+	case "_server":
+		return entry.Metadata.Server, true
+	default:
+		value, exists := entry.Fields[name]
+		return value, exists
+	}
+}
+
+// compileTriggerConditions compile a list of conditions which must all be valid.
+func compileTriggerConditions(values []TriggerValueConfigStruct) (triggerAllCondition, error) {
+	conditions := make(triggerAllCondition, 0, len(values))
+	for i := range values {
+		condition, err := compileTriggerCondition(&values[i])
+		if err != nil {
+			return nil, fmt.Errorf("[%d].%w", i, err)
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions, nil
+}
+
+func compileTriggerCondition(s *TriggerValueConfigStruct) (triggerCondition, error) {
+	kinds := 0
+	for _, isKind := range []bool{s.Field != "" || s.Operator != "", s.All != nil, s.Any != nil, s.Not != nil} {
+		if isKind {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return nil, fmt.Errorf("condition must be either a field condition (field, operator, value) or one group (all, any, not)")
+	}
+
+	switch {
+	case s.All != nil || s.Any != nil:
+		name, values := "all", s.All
+		if s.Any != nil {
+			name, values = "any", s.Any
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("%s must contain at least one condition", name)
+		}
+		conditions, err := compileTriggerConditions(values)
+		if err != nil {
+			return nil, fmt.Errorf("%s%w", name, err)
+		}
+		if s.Any != nil {
+			return triggerAnyCondition(conditions), nil
+		}
+		return conditions, nil
+	case s.Not != nil:
+		condition, err := compileTriggerCondition(s.Not)
+		if err != nil {
+			return nil, fmt.Errorf("not.%w", err)
+		}
+		return &triggerNotCondition{condition: condition}, nil
+	}
+
+	if s.Field == "" {
+		return nil, fmt.Errorf("field is required")
+	}
+	if s.Operator == "" {
+		return nil, fmt.Errorf("operator is required")
+	}
+
+	values, err := triggerValueItems(s)
+	if err != nil {
+		return nil, err
+	}
+	if s.Operator == triggerTypeExists || s.Operator == triggerTypeNotExists {
+		return &triggerExistsCondition{field: s.Field, exists: s.Operator == triggerTypeExists}, nil
+	}
+
+	test, err := compileTriggerTest(s.Operator, values, s.CaseSensitive)
+	if err != nil {
+		return nil, fmt.Errorf("value %w", err)
+	}
+	return &triggerFieldCondition{field: s.Field, test: test}, nil
+}
+
+// triggerValueItems returns values of a field condition, checked against its operator.
+func triggerValueItems(s *TriggerValueConfigStruct) ([]string, error) {
+	var values []string
+	for _, value := range s.Value {
+		// a scalar value of list operators can contain several values, e.g. "500,502,503"
+		if len(s.Value) == 1 && core.SliceContains([]string{triggerTypeIn, triggerTypeNotIn, triggerTypeCIDR}, s.Operator) {
+			for _, item := range strings.Split(value, triggerListSeparator) {
+				values = append(values, strings.TrimSpace(item))
+			}
+			continue
+		}
+		values = append(values, value)
+	}
+	for _, value := range values {
+		if value == "" {
+			return nil, fmt.Errorf("value must not contain empty values")
+		}
+	}
+
+	switch {
+	case s.Operator == triggerTypeExists || s.Operator == triggerTypeNotExists:
+		if len(values) > 0 {
+			return nil, fmt.Errorf("value must be empty with operator \"%s\"", s.Operator)
+		}
+	case len(values) == 0:
+		return nil, fmt.Errorf("value is required with operator \"%s\"", s.Operator)
+	case len(values) > 1 && !core.SliceContains(triggerListOperators, s.Operator):
+		return nil, fmt.Errorf("value must be a single value with operator \"%s\"", s.Operator)
+	}
+	return values, nil
+}
+
+// compileTriggerTest returns the test of field values for operator, values are parsed and patterns compiled once.
+//
+//nolint:gocyclo
+func compileTriggerTest(operator string, values []string, caseSensitive bool) (func(value string) bool, error) {
+	normalize := strings.ToLower
+	if caseSensitive {
+		normalize = func(value string) string { return value }
+	}
+	operand := normalize(values[0])
+
+	switch operator {
+	case triggerTypeRegex, triggerTypeRegexAlias:
+		regex, err := regexp.Compile(values[0])
+		if err != nil {
+			return nil, fmt.Errorf("\"%s\" is not a valid regex: %w", values[0], err)
+		}
+		return regex.MatchString, nil
+	case triggerTypeEqual:
+		return func(value string) bool { return normalize(value) == operand }, nil
+	case triggerTypeNotEqual:
+		return func(value string) bool { return normalize(value) != operand }, nil
+	case triggerTypeContains:
+		return func(value string) bool { return strings.Contains(normalize(value), operand) }, nil
+	case triggerTypeNotContains:
+		return func(value string) bool { return !strings.Contains(normalize(value), operand) }, nil
+	case triggerTypeStartWith:
+		return func(value string) bool { return strings.HasPrefix(normalize(value), operand) }, nil
+	case triggerTypeNotStartWith:
+		return func(value string) bool { return !strings.HasPrefix(normalize(value), operand) }, nil
+	case triggerTypeSeverityGreaterOrEqual, triggerTypeSeverityLowerOrEqual:
+		return compileSeverityTest(operator, values[0])
+	case triggerTypeGreaterThan, triggerTypeGreaterOrEqual, triggerTypeLowerThan, triggerTypeLowerOrEqual,
+		triggerTypeNumberEqual, triggerTypeBetween:
+		return compileNumberTest(operator, values)
+	case triggerTypeIn, triggerTypeNotIn:
+		set := make(map[string]struct{}, len(values))
+		for _, value := range values {
+			set[normalize(value)] = struct{}{}
+		}
+		return func(value string) bool {
+			_, found := set[normalize(value)]
+			return found == (operator == triggerTypeIn)
+		}, nil
+	case triggerTypeCIDR:
+		networks := make([]*net.IPNet, 0, len(values))
+		for _, value := range values {
+			network, err := parseNetwork(value)
+			if err != nil {
+				return nil, err
+			}
+			networks = append(networks, network)
+		}
+		return func(value string) bool {
+			ip := net.ParseIP(strings.TrimSpace(value))
+			if ip == nil {
+				return false
+			}
+			for _, network := range networks {
+				if network.Contains(ip) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case triggerTypeGlob:
+		globs := make([]*regexp.Regexp, 0, len(values))
+		for _, value := range values {
+			glob, err := core.CompileGlob(value, caseSensitive)
+			if err != nil {
+				return nil, err
+			}
+			globs = append(globs, glob)
+		}
+		return func(value string) bool {
+			for _, glob := range globs {
+				if glob.MatchString(value) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown trigger operator: %s", operator)
+	}
+}
+
+// compileNumberTest returns a numeric comparison, units are converted (see core.ParseNumber).
+// Field values which are not numbers never match.
+func compileNumberTest(operator string, values []string) (func(value string) bool, error) {
+	var low, high float64
+	var err error
+	if operator == triggerTypeBetween {
+		low, high, err = parseNumberRange(strings.Join(values, triggerListSeparator))
+	} else {
+		low, err = core.ParseNumber(values[0])
+		high = low
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var compare func(number float64) bool
+	switch operator {
+	case triggerTypeGreaterThan:
+		compare = func(number float64) bool { return number > low }
+	case triggerTypeGreaterOrEqual:
+		compare = func(number float64) bool { return number >= low }
+	case triggerTypeLowerThan:
+		compare = func(number float64) bool { return number < low }
+	case triggerTypeLowerOrEqual:
+		compare = func(number float64) bool { return number <= low }
+	default:
+		compare = func(number float64) bool { return number >= low && number <= high }
+	}
+
+	return func(value string) bool {
+		number, err := core.ParseNumber(value)
+		return err == nil && compare(number)
+	}, nil
+}
+
+// parseNumberRange parse an inclusive range of the "between" operator, e.g. "500,599" or "1s, 5s".
+func parseNumberRange(value string) (float64, float64, error) {
+	lowValue, highValue, ok := strings.Cut(value, triggerListSeparator)
+	if !ok {
+		return 0, 0, fmt.Errorf("range \"%s\" must be \"<min>%s<max>\"", value, triggerListSeparator)
+	}
+	low, err := core.ParseNumber(lowValue)
+	if err != nil {
+		return 0, 0, err
+	}
+	high, err := core.ParseNumber(highValue)
+	if err != nil {
+		return 0, 0, err
+	}
+	if low > high {
+		return 0, 0, fmt.Errorf("range \"%s\" minimum is greater than maximum", value)
+	}
+	return low, high, nil
+}
+
+// parseNetwork parse a network of the "cidr" operator, an address without prefix length is a network of one address.
+func parseNetwork(value string) (*net.IPNet, error) {
+	if !strings.Contains(value, "/") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("\"%s\" is not an IP address or a network", value)
+		}
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 8*net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+
+	_, network, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil, fmt.Errorf("\"%s\" is not an IP address or a network", value)
+	}
+	return network, nil
+}
+
+// match returns true if entry match conditions and expression of trigger.
+func (s *TriggerConfigStruct) match(entry *core.Entry) bool {
+	if !s.condition.match(entry) {
+		return false
+	}
+	if s.expression == nil {
+		return true
+	}
+
+	match, err := s.expression.Evaluate(func(name string) (string, bool) {
+		return entryFieldValue(entry, name)
+	})
+	if err != nil {
+		core.Logger.Debugf(alerterLogPrefix, "unable to evaluate expression of trigger \"%s\" : %s", s.Name, err)
+		return false
+	}
+	return match
+}
+
+// triggerMatcher returns triggers matching an entry. Triggers with a top-level condition on "_parser"
+// ("is" or "in") are indexed by parser name, so an entry is only checked against triggers of its parser
+// and triggers without parser condition. It is built once config is compiled and never modified.
+type triggerMatcher struct {
+	byParser map[string][]indexedTrigger
+	global   []indexedTrigger
+}
+
+type indexedTrigger struct {
+	position int
+	trigger  *TriggerConfigStruct
+}
+
+func newTriggerMatcher(triggers []TriggerConfigStruct) *triggerMatcher {
+	matcher := &triggerMatcher{byParser: map[string][]indexedTrigger{}}
+	for i := range triggers {
+		indexed := indexedTrigger{position: i, trigger: &triggers[i]}
+		parsers := triggerParsers(&triggers[i])
+		if len(parsers) == 0 {
+			matcher.global = append(matcher.global, indexed)
+			continue
+		}
+		for _, parser := range parsers {
+			matcher.byParser[parser] = append(matcher.byParser[parser], indexed)
+		}
+	}
+	return matcher
+}
+
+// triggerParsers returns lowercase names of parsers required by trigger conditions, nil if any parser may match.
+func triggerParsers(trigger *TriggerConfigStruct) []string {
+	for i := range trigger.Values {
+		value := &trigger.Values[i]
+		if value.Field != "_parser" || (value.Operator != triggerTypeEqual && value.Operator != triggerTypeIn) {
+			continue
+		}
+		values, err := triggerValueItems(value)
+		if err != nil {
+			continue
+		}
+		parsers := make([]string, 0, len(values))
+		for _, parser := range values {
+			if !core.SliceContains(parsers, strings.ToLower(parser)) {
+				parsers = append(parsers, strings.ToLower(parser))
+			}
+		}
+		return parsers
+	}
+	return nil
+}
+
+// match returns triggers matching entry, in config order.
+func (matcher *triggerMatcher) match(entry *core.Entry) []*TriggerConfigStruct {
+	if matcher == nil {
+		return nil
+	}
+
+	var matches []*TriggerConfigStruct
+	candidates := matcher.byParser[strings.ToLower(entry.Metadata.Parser)]
+	global := matcher.global
+	for len(candidates) > 0 || len(global) > 0 {
+		var next indexedTrigger
+		if len(global) == 0 || (len(candidates) > 0 && candidates[0].position < global[0].position) {
+			next, candidates = candidates[0], candidates[1:]
+		} else {
+			next, global = global[0], global[1:]
+		}
+		if next.trigger.match(entry) {
+			matches = append(matches, next.trigger)
+		}
+	}
+	return matches
+}
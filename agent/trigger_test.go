@@ -0,0 +1,296 @@
+package agent
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gobana-agent/core"
+)
+
+const triggerTestConfig = `
+application: test
+smtp:
+  from_email: "gobana@example.com"
+parsers:
+  - name: nginx
+    mode: json
+    json_capture_all: true
+    files_included: ["/var/log/nginx.log"]
+alerts:
+  triggers:
+%s
+`
+
+func triggerNames(triggers []*TriggerConfigStruct) []string {
+	names := []string{}
+	for _, trigger := range triggers {
+		names = append(names, trigger.Name)
+	}
+	return names
+}
+
+func TestTriggerConditionGroups(t *testing.T) {
+	config := mustReadTestConfig(t, fmt.Sprintf(triggerTestConfig, `
+    - name: all
+      values:
+        - all:
+            - {field: status, operator: gte, value: "500"}
+            - {field: method, operator: is, value: "POST"}
+    - name: any
+      values:
+        - any:
+            - {field: status, operator: is, value: "502"}
+            - {field: status, operator: is, value: "504"}
+    - name: not
+      values:
+        - not: {field: path, operator: start_with, value: "/health"}
+    - name: nested
+      values:
+        - {field: status, operator: gte, value: "500"}
+        - any:
+            - {field: method, operator: is, value: "DELETE"}
+            - not:
+                any:
+                  - {field: path, operator: start_with, value: "/health"}
+                  - {field: path, operator: start_with, value: "/metrics"}
+    - name: not_missing_field
+      values:
+        - not: {field: user, operator: exists}
+`))
+
+	tests := map[string]struct {
+		fields   map[string]string
+		expected []string
+	}{
+		"server error on post": {
+			fields:   map[string]string{"status": "502", "method": "POST", "path": "/orders", "user": "bob"},
+			expected: []string{"all", "any", "not", "nested"},
+		},
+		"server error on health check": {
+			fields:   map[string]string{"status": "504", "method": "GET", "path": "/healthz"},
+			expected: []string{"any", "not_missing_field"},
+		},
+		"server error on deleted health check": {
+			fields:   map[string]string{"status": "500", "method": "DELETE", "path": "/healthz", "user": "bob"},
+			expected: []string{"nested"},
+		},
+		"server error on metrics": {
+			fields:   map[string]string{"status": "500", "method": "GET", "path": "/metrics", "user": "bob"},
+			expected: []string{"not"},
+		},
+		"success": {
+			fields:   map[string]string{"status": "200", "method": "POST", "path": "/orders", "user": "bob"},
+			expected: []string{"not"},
+		},
+		"missing fields": {
+			fields:   map[string]string{},
+			expected: []string{"not", "not_missing_field"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			entry := &core.Entry{Metadata: core.EntryMetadata{Parser: "nginx"}, Fields: test.fields}
+			if names := triggerNames(config.Alerts.matcher.match(entry)); !reflect.DeepEqual(names, test.expected) {
+				t.Errorf("matching triggers = %v, want %v", names, test.expected)
+			}
+		})
+	}
+}
+
+func TestTriggerConditionGroupsInvalidConfig(t *testing.T) {
+	tests := map[string]struct {
+		values string
+		error  string
+	}{
+		"empty all": {
+			values: `[{all: []}]`,
+			error:  "alerts.triggers[0].values[0].all must contain at least one condition",
+		},
+		"group with field": {
+			values: `[{field: status, operator: is, value: "500", any: [{field: method, operator: is, value: "GET"}]}]`,
+			error:  "alerts.triggers[0].values[0].condition must be either",
+		},
+		"invalid nested condition": {
+			values: `[{any: [{field: status, operator: is, value: "500"}, {not: {field: status, operator: gt, value: "x"}}]}]`,
+			error:  "alerts.triggers[0].values[0].any[1].not.value",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := readTestConfig(t, fmt.Sprintf(triggerTestConfig, "    - {name: invalid, values: "+test.values+"}"))
+			if err == nil {
+				t.Fatalf("config must be invalid")
+			}
+			if !strings.Contains(err.Error(), test.error) {
+				t.Errorf("error %q must contain %q", err, test.error)
+			}
+		})
+	}
+}
+
+func TestTriggerCIDRAndGlob(t *testing.T) {
+	config := mustReadTestConfig(t, fmt.Sprintf(triggerTestConfig, `
+    - name: private
+      values:
+        - {field: client, operator: cidr, value: "10.0.0.0/8, 192.168.1.0/24"}
+    - name: single_addresses
+      values:
+        - {field: client, operator: cidr, value: ["203.0.113.7", "2001:db8::1"]}
+    - name: ipv6_network
+      values:
+        - {field: client, operator: cidr, value: "2001:db8:abcd::/48"}
+    - name: api
+      values:
+        - {field: path, operator: glob, value: ["/api/v?/orders/*", "/admin*"]}
+    - name: api_case_sensitive
+      values:
+        - {field: path, operator: glob, value: "/API/*", case_sensitive: true}
+`))
+
+	tests := map[string]struct {
+		fields   map[string]string
+		expected []string
+	}{
+		"private network":          {fields: map[string]string{"client": "10.42.0.1"}, expected: []string{"private"}},
+		"second network of list":   {fields: map[string]string{"client": "192.168.1.254"}, expected: []string{"private"}},
+		"outside networks":         {fields: map[string]string{"client": "192.168.2.1"}, expected: []string{}},
+		"single ipv4 address":      {fields: map[string]string{"client": " 203.0.113.7 "}, expected: []string{"single_addresses"}},
+		"next ipv4 address":        {fields: map[string]string{"client": "203.0.113.8"}, expected: []string{}},
+		"single ipv6 address":      {fields: map[string]string{"client": "2001:DB8:0::1"}, expected: []string{"single_addresses"}},
+		"ipv6 network":             {fields: map[string]string{"client": "2001:db8:abcd:12::1"}, expected: []string{"ipv6_network"}},
+		"ipv4-mapped ipv6 address": {fields: map[string]string{"client": "::ffff:10.0.0.1"}, expected: []string{"private"}},
+		"not an address":           {fields: map[string]string{"client": "localhost"}, expected: []string{}},
+		"glob":                     {fields: map[string]string{"path": "/api/v2/orders/5/items"}, expected: []string{"api"}},
+		"second glob of list":      {fields: map[string]string{"path": "/administration"}, expected: []string{"api"}},
+		"glob not matching":        {fields: map[string]string{"path": "/api/v10/orders/5"}, expected: []string{}},
+		"glob case insensitive":    {fields: map[string]string{"path": "/API/V1/ORDERS/5"}, expected: []string{"api", "api_case_sensitive"}},
+		"glob case sensitive":      {fields: map[string]string{"path": "/api/orders"}, expected: []string{}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			entry := &core.Entry{Metadata: core.EntryMetadata{Parser: "nginx"}, Fields: test.fields}
+			if names := triggerNames(config.Alerts.matcher.match(entry)); !reflect.DeepEqual(names, test.expected) {
+				t.Errorf("matching triggers = %v, want %v", names, test.expected)
+			}
+		})
+	}
+}
+
+func TestTriggerCIDRAndGlobInvalidConfig(t *testing.T) {
+	tests := map[string]string{
+		"invalid address":     `{field: client, operator: cidr, value: "10.0.0.300"}`,
+		"invalid prefix":      `{field: client, operator: cidr, value: "10.0.0.0/33"}`,
+		"empty network":       `{field: client, operator: cidr, value: "10.0.0.0/8,"}`,
+		"unclosed glob class": `{field: path, operator: glob, value: "/api/[v1"}`,
+		"invalid glob range":  `{field: path, operator: glob, value: "/api/[z-a]"}`,
+	}
+
+	for name, value := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := readTestConfig(t, fmt.Sprintf(triggerTestConfig, "    - {name: invalid, values: ["+value+"]}"))
+			if err == nil || !strings.Contains(err.Error(), "alerts.triggers[0].values[0].value") {
+				t.Errorf("config must be invalid, got error %v", err)
+			}
+		})
+	}
+}
+
+func TestTriggerMatcherParserIndex(t *testing.T) {
+	config := mustReadTestConfig(t, fmt.Sprintf(triggerTestConfig, `
+    - name: nginx_errors
+      values:
+        - {field: _parser, operator: is, value: "Nginx"}
+        - {field: status, operator: gte, value: "500"}
+    - name: all_errors
+      values:
+        - {field: status, operator: gte, value: "500"}
+    - name: web_errors
+      values:
+        - {field: _parser, operator: in, value: ["nginx", "apache"]}
+        - {field: status, operator: gte, value: "500"}
+    - name: not_nginx
+      values:
+        - {field: _parser, operator: is_not, value: "nginx"}
+    - name: grouped_parser
+      values:
+        - any:
+            - {field: _parser, operator: is, value: "php"}
+`))
+	matcher := config.Alerts.matcher
+
+	indexed := map[string][]string{}
+	for parser, triggers := range matcher.byParser {
+		for _, trigger := range triggers {
+			indexed[parser] = append(indexed[parser], trigger.trigger.Name)
+		}
+	}
+	expectedIndex := map[string][]string{"nginx": {"nginx_errors", "web_errors"}, "apache": {"web_errors"}}
+	if !reflect.DeepEqual(indexed, expectedIndex) {
+		t.Errorf("indexed triggers = %v, want %v", indexed, expectedIndex)
+	}
+	if len(matcher.global) != 3 {
+		t.Errorf("global triggers = %d, want 3", len(matcher.global))
+	}
+
+	tests := map[string]struct {
+		parser   string
+		expected []string
+	}{
+		"indexed parser":           {parser: "nginx", expected: []string{"nginx_errors", "all_errors", "web_errors"}},
+		"indexed parser case":      {parser: "NGINX", expected: []string{"nginx_errors", "all_errors", "web_errors"}},
+		"parser of list":           {parser: "apache", expected: []string{"all_errors", "web_errors", "not_nginx"}},
+		"parser in group":          {parser: "php", expected: []string{"all_errors", "not_nginx", "grouped_parser"}},
+		"parser without triggers":  {parser: "mysql", expected: []string{"all_errors", "not_nginx"}},
+		"entry without any parser": {parser: "", expected: []string{"all_errors"}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			entry := &core.Entry{Metadata: core.EntryMetadata{Parser: test.parser}, Fields: map[string]string{"status": "503"}}
+			if names := triggerNames(matcher.match(entry)); !reflect.DeepEqual(names, test.expected) {
+				t.Errorf("matching triggers = %v, want %v", names, test.expected)
+			}
+		})
+	}
+}
+
+// BenchmarkTriggerMatcher compare triggers indexed by parser with the same triggers evaluated for each entry
+// (the parser condition is wrapped in a group, which is not indexed).
+func BenchmarkTriggerMatcher(b *testing.B) {
+	const parsers = 100
+
+	for _, count := range []int{1000, 10000} {
+		for _, indexed := range []bool{true, false} {
+			var triggers strings.Builder
+			for i := 0; i < count; i++ {
+				parserCondition := fmt.Sprintf(`{field: _parser, operator: is, value: "parser%d"}`, i%parsers)
+				if !indexed {
+					parserCondition = "{all: [" + parserCondition + "]}"
+				}
+				fmt.Fprintf(&triggers, "    - {name: trigger%d, values: [%s, {field: status, operator: gte, value: \"%d\"}]}\n",
+					i, parserCondition, 400+i%200)
+			}
+			config := mustReadTestConfig(b, fmt.Sprintf(triggerTestConfig, triggers.String()))
+			entry := &core.Entry{
+				Metadata: core.EntryMetadata{Parser: "parser7"},
+				Fields:   map[string]string{"status": "502", "method": "GET", "path": "/orders"},
+			}
+
+			mode := "indexed"
+			if !indexed {
+				mode = "not_indexed"
+			}
+			b.Run(fmt.Sprintf("%s/%d", mode, count), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					config.Alerts.matcher.match(entry)
+				}
+			})
+		}
+	}
+}
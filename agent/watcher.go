@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"reflect"
 	"regexp"
 	"strings"
 	"sync"
@@ -21,10 +20,23 @@ const (
 	watcherLogPrefix = "watcher"
 	watcherTimer     = 1 * time.Second
 
+	watcherErrorLogInterval = 10 * time.Second
+
 	eventNameEntryDiscover = "agent.log.discover"
 
-	parserModeRegex = "regex"
-	parserModeJSON  = "json"
+	parserModeRegex      = "regex"
+	parserModeJSON       = "json"
+	parserModeMySQLSlow  = "mysql_slow"
+	parserModePostgreSQL = "postgresql"
+	parserModeAuditd     = "auditd"
+	parserModeJSONStream = "json_stream"
+	parserModeXML        = "xml"
+
+	dateExtractOnErrorCaptureTime = "capture_time"
+
+	onParseErrorKeepRaw       = "keep_raw"
+	onParseErrorKeepWithField = "keep_with_field"
+	fieldNameParseError       = "_parse_error"
 )
 
 type EntryDiscoverEvent struct {
@@ -51,6 +63,7 @@ type WatcherProcess struct {
 
 	currentTails map[string]*currentWatching
 	regexCache   map[string]*regexp.Regexp
+	errorLogger  *core.LogRateLimiter
 }
 
 func (watcher *WatcherProcess) Name() string {
@@ -59,6 +72,7 @@ func (watcher *WatcherProcess) Name() string {
 
 func (watcher *WatcherProcess) Run() error {
 	watcher.regexCache = make(map[string]*regexp.Regexp)
+	watcher.errorLogger = core.NewLogRateLimiter(core.Logger, watcherErrorLogInterval)
 	watcher.currentTails = map[string]*currentWatching{}
 	watcher.exitChan = make(chan bool)
 
@@ -148,25 +162,12 @@ func (watcher *WatcherProcess) startWatchFile(parser *ParserConfigStruct, file s
 	watcher.currentTails[watcher.genTailKey(parser, file)] = cur
 	watcher.mu.Unlock()
 
-	for line := range t.Lines {
-		go func(l *tail.Line) {
-			core.Logger.Debugf(watcherLogPrefix, "Receive line: %s", l.Text)
-
-			var entry *core.Entry
-			var err error
-			entry, err = watcher.handleLine(cur, l)
-			if err != nil {
-				core.Logger.Errorf(watcherLogPrefix, "Error while handle line with parser \"%s\": %s", parser.Name, err)
-				return
-			}
-
-			core.Logger.Debugf(watcherLogPrefix, "Line handled")
-			for k, v := range entry.Fields {
-				core.Logger.Debugf(watcherLogPrefix, "Field %s: %s", k, v)
-			}
-
-			core.EventDispatcher.Dispatch(&EntryDiscoverEvent{Entry: entry})
-		}(line)
+	if assembler := newRecordAssembler(parser); assembler != nil {
+		watcher.assembleLines(cur, assembler)
+	} else {
+		for line := range t.Lines {
+			go watcher.processLine(cur, line)
+		}
 	}
 
 	watcher.mu.Lock()
@@ -174,6 +175,45 @@ func (watcher *WatcherProcess) startWatchFile(parser *ParserConfigStruct, file s
 	watcher.mu.Unlock()
 }
 
+// processLine handle a line (or a multi-line record) and dispatch resulting entry.
+func (watcher *WatcherProcess) processLine(fileWatcher *currentWatching, line *tail.Line) {
+	parser := fileWatcher.parser
+	core.Logger.Debugf(watcherLogPrefix, "Receive line: %s", line.Text)
+
+	entry, err := watcher.handleLine(fileWatcher, line)
+	if err != nil {
+		watcher.errorLogger.Errorf(parser.Name, watcherLogPrefix, "Error while handle line with parser \"%s\": %s", parser.Name, err)
+		return
+	}
+
+	if !processEntry(parser, entry) {
+		return
+	}
+
+	core.Logger.Debugf(watcherLogPrefix, "Line handled")
+	for k, v := range entry.Fields {
+		core.Logger.Debugf(watcherLogPrefix, "Field %s: %s", k, v)
+	}
+
+	core.EventDispatcher.Dispatch(&EntryDiscoverEvent{Entry: entry})
+}
+
+// processEntry apply processors then filters, it returns false if the entry must be discarded.
+// Filters are applied on processed fields, so they can use normalised field names.
+func processEntry(parser *ParserConfigStruct, entry *core.Entry) bool {
+	if !applyProcessors(parser, entry) {
+		core.Logger.Debugf(watcherLogPrefix, "Line dropped by processors")
+		return false
+	}
+
+	if !filterEntry(parser, entry) {
+		core.Logger.Debugf(watcherLogPrefix, "Line dropped by filters")
+		return false
+	}
+
+	return true
+}
+
 func (watcher *WatcherProcess) endWatchFromTailKey(tailKey string) {
 	if _, ok := watcher.currentTails[tailKey]; !ok {
 		return
@@ -206,27 +246,72 @@ func (watcher *WatcherProcess) handleLine(fileWatcher *currentWatching, line *ta
 	}
 
 	// parse log line
-	switch {
-	case fileWatcher.parser.Mode == parserModeRegex:
-		if err := watcher.handleParseRegex(fileWatcher, entry, line.Text); err != nil {
-			return nil, fmt.Errorf("error while handle regex: %w", err)
-		}
-	case fileWatcher.parser.Mode == parserModeJSON:
-		if err := watcher.handleParseJSON(fileWatcher, entry, line.Text); err != nil {
-			return nil, fmt.Errorf("error while handle json: %w", err)
+	if err := watcher.parseLine(fileWatcher, entry, line.Text); err != nil {
+		switch fileWatcher.parser.OnParseError {
+		case onParseErrorKeepRaw:
+			entry.Fields = map[string]string{}
+		case onParseErrorKeepWithField:
+			entry.Fields = map[string]string{fieldNameParseError: err.Error()}
+		default:
+			return nil, err
 		}
-	default:
-		return nil, fmt.Errorf("unknown mode %s", fileWatcher.parser.Mode)
+		watcher.errorLogger.Errorf(
+			fileWatcher.parser.Name, watcherLogPrefix,
+			"Error while handle line with parser \"%s\", keep raw line: %s", fileWatcher.parser.Name, err,
+		)
+		// date field is not captured, use the time when the line was read
+		entry.Date = entry.Metadata.CaptureDate
 	}
 
+	// parse captured fields with sub-parsers
+	applySubParsers(fileWatcher.parser, entry)
+
 	// extract date from entry
 	if err := watcher.extractDate(fileWatcher, entry); err != nil {
 		return nil, fmt.Errorf("error while extract date: %w", err)
 	}
 
+	// normalise severity
+	applySeverity(fileWatcher.parser, entry)
+
 	return entry, nil
 }
 
+func (watcher *WatcherProcess) parseLine(fileWatcher *currentWatching, entry *core.Entry, line string) error {
+	switch {
+	case fileWatcher.parser.Mode == parserModeRegex:
+		if err := watcher.handleParseRegex(fileWatcher, entry, line); err != nil {
+			return fmt.Errorf("error while handle regex: %w", err)
+		}
+	case fileWatcher.parser.Mode == parserModeJSON || fileWatcher.parser.Mode == parserModeJSONStream:
+		if err := watcher.handleParseJSON(fileWatcher, entry, line); err != nil {
+			return fmt.Errorf("error while handle json: %w", err)
+		}
+	case fileWatcher.parser.Mode == parserModeXML:
+		root, err := parseXMLRecord(line)
+		if err != nil {
+			return fmt.Errorf("error while handle xml: %w (record: %s)", err, line)
+		}
+		extractXMLFields(entry.Fields, root, fileWatcher.parser.xmlSelectors)
+	case fileWatcher.parser.Mode == parserModeMySQLSlow:
+		if err := parseMySQLSlowRecord(entry.Fields, line); err != nil {
+			return fmt.Errorf("error while handle mysql slow query record: %w", err)
+		}
+	case fileWatcher.parser.Mode == parserModePostgreSQL:
+		if err := parsePostgreSQLRecord(entry.Fields, line); err != nil {
+			return fmt.Errorf("error while handle postgresql record: %w", err)
+		}
+	case fileWatcher.parser.Mode == parserModeAuditd:
+		if err := parseAuditdRecord(entry.Fields, line); err != nil {
+			return fmt.Errorf("error while handle auditd event: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown mode %s", fileWatcher.parser.Mode)
+	}
+
+	return nil
+}
+
 func (watcher *WatcherProcess) handleParseRegex(fileWatcher *currentWatching, entry *core.Entry, line string) error {
 	var regex *regexp.Regexp
 	var ok bool
@@ -262,65 +347,73 @@ func (watcher *WatcherProcess) handleParseJSON(fileWatcher *currentWatching, ent
 	if err := json.Unmarshal([]byte(line), &jsonData); err != nil {
 		return fmt.Errorf("unable to parse line as json: %w (line: %s)", err, line)
 	}
-	for internalFieldName, jsonField := range fileWatcher.parser.JSONFields {
-		// json key not exists and key contain "." => use json path
-		if _, ok := jsonData[jsonField]; !ok && strings.Contains(jsonField, ".") {
-			splitByDot := strings.Split(jsonField, ".")
-			var cur interface{} = jsonData
-			if len(splitByDot) > 1 {
-				for _, part := range splitByDot {
-					if _, ok := cur.(map[string]interface{})[part]; ok {
-						cur = cur.(map[string]interface{})[part]
-					} else {
-						cur = nil
-						break
-					}
-				}
-				jsonData[jsonField] = cur
-			}
-		}
 
-		// jsonField not exists
-		if _, ok := jsonData[jsonField]; !ok {
-			continue
-		}
-		// jsonField is nil
-		if jsonData[jsonField] == nil {
-			entry.Fields[internalFieldName] = ""
+	extractJSONFields(
+		entry.Fields, jsonData, fileWatcher.parser.jsonSelectors,
+		fileWatcher.parser.JSONCaptureAll, fileWatcher.parser.jsonFlattenLimits(),
+	)
+
+	return nil
+}
+
+// extractJSONFields write json values matching selectors into fields (all values are flattened if captureAll is set).
+func extractJSONFields(
+	fields map[string]string,
+	jsonData interface{},
+	selectors map[string]*core.JSONPath,
+	captureAll bool,
+	limits core.JSONFlattenLimits,
+) {
+	// keys limit is shared by captured fields and wildcard selectors, so it bounds the whole entry
+	remainingLimits := limits
+
+	// capture all fields, explicit mapping below overrides captured keys
+	if captureAll {
+		remainingLimits.MaxKeys -= core.FlattenJSON(fields, "", jsonData, remainingLimits)
+	}
+
+	// sorted to give the remaining keys to the same selectors for each entry
+	for _, internalFieldName := range core.SortedKeys(selectors) {
+		selector := selectors[internalFieldName]
+		matches := selector.Select(jsonData)
+		// jsonField not exists : keep previous behavior, an unknown path is captured as an empty value
+		if len(matches) == 0 {
+			if !selector.HasWildcard() {
+				fields[internalFieldName] = ""
+			}
 			continue
 		}
 
-		switch reflect.TypeOf(jsonData[jsonField]).Kind() {
-		case reflect.String:
-			entry.Fields[internalFieldName] = jsonData[jsonField].(string)
-		case reflect.Float64:
-			if core.IsDecimal(jsonData[jsonField].(float64)) {
-				entry.Fields[internalFieldName] = fmt.Sprintf("%d", int64(jsonData[jsonField].(float64)))
-			} else {
-				entry.Fields[internalFieldName] = fmt.Sprintf("%f", jsonData[jsonField])
+		// wildcard selector (e.g. "context.*", "items[*].id") => flatten values under internal field name
+		if selector.HasWildcard() {
+			for _, match := range matches {
+				if remainingLimits.MaxKeys <= 0 {
+					break
+				}
+				prefix := strings.Join(append([]string{internalFieldName}, match.Keys...), ".")
+				remainingLimits.MaxKeys -= core.FlattenJSON(fields, prefix, match.Value, remainingLimits)
 			}
-		case reflect.Int:
-			entry.Fields[internalFieldName] = fmt.Sprintf("%d", jsonData[jsonField].(int64))
-		case reflect.Map:
-			content, _ := json.Marshal(jsonData[jsonField])
-			entry.Fields[internalFieldName] = string(content)
-		default:
-			entry.Fields[internalFieldName] = fmt.Sprintf("%v", jsonData[jsonField])
+			continue
 		}
-	}
 
-	return nil
+		fields[internalFieldName] = core.JSONValueToString(matches[0].Value)
+	}
 }
 
 func (watcher *WatcherProcess) extractDate(fileWatcher *currentWatching, entry *core.Entry) error {
+	dateExtract := &fileWatcher.parser.DateExtract
 	// date extraction
-	if fileWatcher.parser.DateExtract.Field != "" {
+	if dateExtract.Field != "" {
 		// search for date field
-		if _, ok := entry.Fields[fileWatcher.parser.DateExtract.Field]; ok {
+		if value, ok := entry.Fields[dateExtract.Field]; ok {
 			// date field found
-			date, err := time.Parse(fileWatcher.parser.DateExtract.Format, entry.Fields[fileWatcher.parser.DateExtract.Field])
+			date, err := dateExtract.parser.Parse(value, entry.Metadata.CaptureDate)
 			if err != nil {
-				core.Logger.Errorf(watcherLogPrefix, "Error while parsing date \"%s\": %s", entry.Fields[fileWatcher.parser.DateExtract.Field], err)
+				if dateExtract.OnError == dateExtractOnErrorCaptureTime {
+					core.Logger.Debugf(watcherLogPrefix, "Unable to parse date, use capture time: %s", err)
+					entry.Date = entry.Metadata.CaptureDate
+					return nil
+				}
 				return err
 			}
 			entry.Date = date
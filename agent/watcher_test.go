@@ -0,0 +1,192 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/nxadm/tail"
+
+	"gobana-agent/core"
+)
+
+func compileTestJSONSelectors(t *testing.T, paths map[string]string) map[string]*core.JSONPath {
+	t.Helper()
+	selectors := make(map[string]*core.JSONPath, len(paths))
+	for name, path := range paths {
+		selector, err := core.CompileJSONPath(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		selectors[name] = selector
+	}
+	return selectors
+}
+
+func TestExtractJSONFieldsKeysLimit(t *testing.T) {
+	document := `{"a": 1, "b": 2, "c": 3, "context": {"d": 4, "e": 5, "f": 6}, "extra": {"g": 7, "h": 8}}`
+	var jsonData interface{}
+	if err := json.Unmarshal([]byte(document), &jsonData); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		captureAll bool
+		paths      map[string]string
+		maxKeys    int
+		expected   int
+	}{
+		"capture all":                       {captureAll: true, maxKeys: 4, expected: 4},
+		"wildcard selectors":                {paths: map[string]string{"ctx": "context.*", "ext": "extra.*"}, maxKeys: 4, expected: 4},
+		"capture all and wildcard selector": {captureAll: true, paths: map[string]string{"ctx": "context.*"}, maxKeys: 6, expected: 6},
+		"under limit":                       {paths: map[string]string{"ctx": "context.*", "ext": "extra.*"}, maxKeys: 10, expected: 5},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			fields := map[string]string{}
+			limits := core.JSONFlattenLimits{MaxDepth: 5, MaxKeys: test.maxKeys}
+			extractJSONFields(fields, jsonData, compileTestJSONSelectors(t, test.paths), test.captureAll, limits)
+			if len(fields) != test.expected {
+				t.Errorf("fields = %v (%d keys), want %d keys", fields, len(fields), test.expected)
+			}
+		})
+	}
+}
+
+func TestExtractJSONFieldsMissingPath(t *testing.T) {
+	var jsonData interface{}
+	if err := json.Unmarshal([]byte(`{"user": {"id": 42}, "tags": []}`), &jsonData); err != nil {
+		t.Fatal(err)
+	}
+	selectors := compileTestJSONSelectors(t, map[string]string{
+		"user_id":   "user.id",
+		"user_name": "user.name",
+		"missing":   "request.headers.host",
+		"tags":      "tags[*]",
+	})
+
+	fields := map[string]string{}
+	extractJSONFields(fields, jsonData, selectors, false, core.JSONFlattenLimits{MaxDepth: 5, MaxKeys: 200})
+
+	expected := map[string]string{"user_id": "42", "user_name": "", "missing": ""}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("fields = %v, want %v", fields, expected)
+	}
+}
+
+func TestProcessEntryFiltersProcessedFields(t *testing.T) {
+	config := mustReadTestConfig(t, `
+application: test
+smtp:
+  from_email: "gobana@example.com"
+parsers:
+  - name: app
+    mode: json
+    json_capture_all: true
+    files_included: ["/var/log/app.log"]
+    processors:
+      - {type: rename, field: lvl, target: level}
+      - {type: lowercase, field: level}
+    drop_when:
+      - {field: level, operator: is, value: "debug", case_sensitive: true}
+`)
+	parser := config.Parsers[0]
+
+	tests := map[string]struct {
+		fields map[string]string
+		kept   bool
+	}{
+		"renamed and normalised field": {fields: map[string]string{"lvl": "DEBUG"}, kept: false},
+		"other level":                  {fields: map[string]string{"lvl": "ERROR"}, kept: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			entry := &core.Entry{Fields: test.fields}
+			if kept := processEntry(parser, entry); kept != test.kept {
+				t.Errorf("processEntry() = %t, want %t (fields %v)", kept, test.kept, entry.Fields)
+			}
+		})
+	}
+}
+
+func TestHandleLineParseError(t *testing.T) {
+	captureDate := time.Date(2024, 10, 11, 0, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		mode     string
+		line     string
+		fields   map[string]string
+		date     time.Time
+		hasError bool
+	}{
+		"parsed line": {
+			mode: "drop",
+			line: "2024-10-10T13:55:36Z ERROR user=bob action=login",
+			fields: map[string]string{
+				"date": "2024-10-10T13:55:36Z", "level": "ERROR", "context": "user=bob action=login",
+				"user": "bob", "action": "login", "_severity": "error",
+			},
+			date: time.Date(2024, 10, 10, 13, 55, 36, 0, time.UTC),
+		},
+		"drop": {mode: "drop", line: "not a log line", hasError: true},
+		"keep raw": {
+			mode:   onParseErrorKeepRaw,
+			line:   "not a log line",
+			fields: map[string]string{"_severity": "unknown"},
+			date:   captureDate,
+		},
+		"keep with field": {
+			mode: onParseErrorKeepWithField,
+			line: "not a log line",
+			fields: map[string]string{
+				fieldNameParseError: "error while handle regex: line not match regex (not a log line)", "_severity": "unknown",
+			},
+			date: captureDate,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := mustReadTestConfig(t, fmt.Sprintf(`
+application: test
+smtp:
+  from_email: "gobana@example.com"
+parsers:
+  - name: app
+    mode: regex
+    regex_pattern: '^(?P<date>\S+) (?P<level>[A-Z]+) (?P<context>.*)$'
+    on_parse_error: %s
+    files_included: ["/var/log/app.log"]
+    date_extract: {field: date, format: "%%Y-%%m-%%dT%%H:%%M:%%SZ"}
+    sub_parsers:
+      - {field: context, mode: kv}
+`, test.mode))
+			fileWatcher := &currentWatching{parser: config.Parsers[0], fileName: "/var/log/app.log"}
+			watcher := &WatcherProcess{
+				regexCache:  map[string]*regexp.Regexp{},
+				errorLogger: core.NewLogRateLimiter(core.Logger, time.Minute),
+			}
+
+			entry, err := watcher.handleLine(fileWatcher, &tail.Line{Text: test.line, Time: captureDate})
+			if (err != nil) != test.hasError {
+				t.Fatalf("handleLine() error = %v, want error %t", err, test.hasError)
+			}
+			if test.hasError {
+				return
+			}
+			if !reflect.DeepEqual(entry.Fields, test.fields) {
+				t.Errorf("fields = %v, want %v", entry.Fields, test.fields)
+			}
+			if !entry.Date.Equal(test.date) {
+				t.Errorf("date = %s, want %s", entry.Date, test.date)
+			}
+			if entry.Raw != test.line || entry.Metadata.Parser != "app" {
+				t.Errorf("raw line and metadata must be kept, got %q, %v", entry.Raw, entry.Metadata)
+			}
+		})
+	}
+}
@@ -19,6 +19,12 @@ type SMTPConfig struct {
 	FromEmail  string `yaml:"from_email" validate:"required,email" default:"gobana@localhost"`
 }
 
+// ConfigCompiler is implemented by configs which need to prepare runtime structures
+// (compiled patterns, selectors...) once loaded and validated.
+type ConfigCompiler interface {
+	Compile() error
+}
+
 func ReadConfig(filename string, config interface{}) error {
 	var readErr error
 	data, readErr := os.ReadFile(filename)
@@ -34,6 +40,12 @@ func ReadConfig(filename string, config interface{}) error {
 		return fmt.Errorf("validation error : %w", validationErr)
 	}
 
+	if compiler, ok := config.(ConfigCompiler); ok {
+		if err := compiler.Compile(); err != nil {
+			return fmt.Errorf("validation error : %w", err)
+		}
+	}
+
 	return nil
 }
 
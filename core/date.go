@@ -0,0 +1,235 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Epoch formats accepted by DateParser.
+const (
+	DateFormatUnix   = "unix"
+	DateFormatUnixMs = "unix_ms"
+	DateFormatUnixUs = "unix_us"
+	DateFormatUnixNs = "unix_ns"
+
+	// a date without year more than this delay in the future is considered from previous year
+	dateYearInferenceTolerance = 24 * time.Hour
+)
+
+var strftimeDirectives = map[byte]string{
+	'Y': "2006",
+	'y': "06",
+	'm': "01",
+	'd': "02",
+	'e': "_2",
+	'H': "15",
+	'I': "03",
+	'M': "04",
+	'S': "05",
+	'f': "000000",
+	'L': "000",
+	'z': "-0700",
+	'Z': "MST",
+	'b': "Jan",
+	'h': "Jan",
+	'B': "January",
+	'a': "Mon",
+	'A': "Monday",
+	'p': "PM",
+	'j': "002",
+	'T': "15:04:05",
+	'D': "01/02/06",
+	'F': "2006-01-02",
+	'%': "%",
+}
+
+// DateParser parse dates using a list of layouts tried in order.
+type DateParser struct {
+	layouts  []dateLayout
+	location *time.Location
+}
+
+type dateLayout struct {
+	source   string
+	layout   string
+	epoch    string
+	withYear bool
+}
+
+// NewDateParser compile date formats. A format is either :
+//   - a Go layout (e.g. "2006-01-02T15:04:05Z07:00")
+//   - a strftime layout (e.g. "%Y-%m-%d %H:%M:%S")
+//   - an epoch format : "unix" (seconds, may contain decimals), "unix_ms", "unix_us" or "unix_ns"
+//
+// Dates without offset are read in location.
+func NewDateParser(formats []string, location *time.Location) (*DateParser, error) {
+	parser := &DateParser{location: location}
+	if parser.location == nil {
+		parser.location = time.UTC
+	}
+
+	for _, format := range formats {
+		layout := dateLayout{source: format}
+		switch format {
+		case DateFormatUnix, DateFormatUnixMs, DateFormatUnixUs, DateFormatUnixNs:
+			layout.epoch = format
+			layout.withYear = true
+		default:
+			layout.layout = format
+			layout.withYear = strings.Contains(format, "06")
+			if strings.Contains(format, "%") {
+				converted, withYear, err := convertStrftime(format)
+				if err != nil {
+					return nil, err
+				}
+				// year is read from directives, a literal text may contain "06"
+				layout.layout, layout.withYear = converted, withYear
+			}
+		}
+		parser.layouts = append(parser.layouts, layout)
+	}
+
+	return parser, nil
+}
+
+// Parse try each layout in order and returns the first successfully parsed date.
+// Year of dates without year (e.g. syslog "Jan  2 15:04:05") is inferred from now.
+func (parser *DateParser) Parse(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	tried := make([]string, 0, len(parser.layouts))
+	for _, layout := range parser.layouts {
+		var date time.Time
+		var err error
+		if layout.epoch != "" {
+			date, err = parseEpoch(value, layout.epoch)
+		} else {
+			date, err = time.ParseInLocation(layout.layout, value, parser.location)
+		}
+		if err != nil {
+			tried = append(tried, layout.source)
+			continue
+		}
+
+		if !layout.withYear {
+			date = inferYear(date, now)
+		}
+		return date, nil
+	}
+
+	return time.Time{}, fmt.Errorf("date \"%s\" does not match any format (tried \"%s\")", value, strings.Join(tried, "\", \""))
+}
+
+func parseEpoch(value, format string) (time.Time, error) {
+	if format == DateFormatUnix {
+		return parseEpochSeconds(value)
+	}
+
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid epoch: %w", err)
+	}
+	switch format {
+	case DateFormatUnixMs:
+		return time.UnixMilli(number).UTC(), nil
+	case DateFormatUnixUs:
+		return time.UnixMicro(number).UTC(), nil
+	default:
+		return time.Unix(0, number).UTC(), nil
+	}
+}
+
+// parseEpochSeconds parse seconds with optional decimals (e.g. "1364481363.243") without float rounding.
+// Sign applies to decimals too, e.g. "-1.5" is 1.5 seconds before epoch.
+func parseEpochSeconds(value string) (time.Time, error) {
+	unsigned, negative := strings.CutPrefix(value, "-")
+	integer, fraction, _ := strings.Cut(unsigned, ".")
+	if !isEpochDigits(integer) || (fraction != "" && !isEpochDigits(fraction)) {
+		return time.Time{}, fmt.Errorf("invalid epoch \"%s\"", value)
+	}
+	seconds, err := strconv.ParseInt(integer, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid epoch: %w", err)
+	}
+
+	var nanoseconds int64
+	if fraction != "" {
+		if len(fraction) > 9 {
+			fraction = fraction[:9]
+		}
+		fraction += strings.Repeat("0", 9-len(fraction))
+		nanoseconds, _ = strconv.ParseInt(fraction, 10, 64)
+	}
+
+	if negative {
+		seconds, nanoseconds = -seconds, -nanoseconds
+	}
+	return time.Unix(seconds, nanoseconds).UTC(), nil
+}
+
+func isEpochDigits(value string) bool {
+	if value == "" {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		if value[i] < '0' || value[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// inferYear returns date in the current year, or in the previous one if it is too far in the future.
+// February 29 is set in the last leap year.
+func inferYear(date, now time.Time) time.Time {
+	now = now.In(date.Location())
+	limit := now.Add(dateYearInferenceTolerance)
+	for year := now.Year(); ; year-- {
+		inferred := time.Date(year, date.Month(), date.Day(), date.Hour(), date.Minute(), date.Second(), date.Nanosecond(), date.Location())
+		// an invalid date is normalized to another day
+		if inferred.Day() == date.Day() && !inferred.After(limit) {
+			return inferred
+		}
+	}
+}
+
+// StrftimeToLayout convert a strftime layout to a Go layout.
+func StrftimeToLayout(format string) (string, error) {
+	layout, _, err := convertStrftime(format)
+	return layout, err
+}
+
+// convertStrftime convert a strftime layout to a Go layout and returns whether it contains a year directive.
+func convertStrftime(format string) (string, bool, error) {
+	var layout strings.Builder
+	withYear := false
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			layout.WriteByte(format[i])
+			continue
+		}
+		if i+1 >= len(format) {
+			return "", false, fmt.Errorf("invalid strftime format \"%s\": trailing %%", format)
+		}
+		i++
+		// "%:z" => offset with colon
+		if format[i] == ':' && i+1 < len(format) && format[i+1] == 'z' {
+			layout.WriteString("-07:00")
+			i++
+			continue
+		}
+		directive, ok := strftimeDirectives[format[i]]
+		if !ok {
+			return "", false, fmt.Errorf("invalid strftime format \"%s\": unsupported directive %%%c", format, format[i])
+		}
+		switch format[i] {
+		case 'Y', 'y', 'D', 'F':
+			withYear = true
+		}
+		layout.WriteString(directive)
+	}
+
+	return layout.String(), withYear, nil
+}
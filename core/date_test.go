@@ -0,0 +1,162 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStrftimeToLayout(t *testing.T) {
+	tests := map[string]struct {
+		format   string
+		layout   string
+		hasError bool
+	}{
+		"iso":              {format: "%Y-%m-%dT%H:%M:%S%z", layout: "2006-01-02T15:04:05-0700"},
+		"offset colon":     {format: "%F %T%:z", layout: "2006-01-02 15:04:05-07:00"},
+		"fractions":        {format: "%H:%M:%S.%L / %S.%f", layout: "15:04:05.000 / 05.000000"},
+		"syslog":           {format: "%b %e %H:%M:%S", layout: "Jan _2 15:04:05"},
+		"names":            {format: "%a, %d %B %y %I:%M %p %Z", layout: "Mon, 02 January 06 03:04 PM MST"},
+		"percent":          {format: "100%% %D", layout: "100% 01/02/06"},
+		"unsupported":      {format: "%Y-%Q", hasError: true},
+		"trailing percent": {format: "%Y %", hasError: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			layout, err := StrftimeToLayout(test.format)
+			if (err != nil) != test.hasError {
+				t.Fatalf("StrftimeToLayout(%q) error = %v, want error %t", test.format, err, test.hasError)
+			}
+			if layout != test.layout {
+				t.Errorf("StrftimeToLayout(%q) = %q, want %q", test.format, layout, test.layout)
+			}
+		})
+	}
+}
+
+func TestDateParser(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip(err)
+	}
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		formats  []string
+		location *time.Location
+		value    string
+		now      time.Time
+		expected time.Time
+		hasError bool
+	}{
+		"go layout": {
+			formats: []string{time.RFC3339Nano}, value: "2024-10-10T13:55:36.123+02:00",
+			expected: time.Date(2024, 10, 10, 11, 55, 36, 123000000, time.UTC),
+		},
+		"strftime in location": {
+			formats: []string{"%Y-%m-%d %H:%M:%S"}, location: paris, value: "2024-10-10 13:55:36",
+			expected: time.Date(2024, 10, 10, 11, 55, 36, 0, time.UTC),
+		},
+		"formats tried in order": {
+			formats: []string{"%Y-%m-%d %H:%M:%S", "%d/%b/%Y:%H:%M:%S %z"}, value: "10/Oct/2024:13:55:36 -0700",
+			expected: time.Date(2024, 10, 10, 20, 55, 36, 0, time.UTC),
+		},
+		"no matching format": {
+			formats: []string{"%Y-%m-%d", DateFormatUnix}, value: "yesterday", hasError: true,
+		},
+		"year inferred": {
+			formats: []string{"%b %e %H:%M:%S"}, value: "Mar  9 08:00:00",
+			expected: time.Date(2025, 3, 9, 8, 0, 0, 0, time.UTC),
+		},
+		"year inferred within tolerance": {
+			formats: []string{"%b %e %H:%M:%S"}, value: "Mar 11 08:00:00",
+			expected: time.Date(2025, 3, 11, 8, 0, 0, 0, time.UTC),
+		},
+		"previous year": {
+			formats: []string{"%b %e %H:%M:%S"}, value: "Dec 31 23:59:59", now: time.Date(2025, 1, 1, 0, 0, 5, 0, time.UTC),
+			expected: time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC),
+		},
+		"february 29 in last leap year": {
+			formats: []string{"%b %e %H:%M:%S"}, value: "Feb 29 10:00:00",
+			expected: time.Date(2024, 2, 29, 10, 0, 0, 0, time.UTC),
+		},
+		"february 29 in current leap year": {
+			formats: []string{"%b %e %H:%M:%S"}, value: "Feb 29 10:00:00", now: time.Date(2028, 3, 1, 0, 0, 0, 0, time.UTC),
+			expected: time.Date(2028, 2, 29, 10, 0, 0, 0, time.UTC),
+		},
+		"literal text is not a year": {
+			formats: []string{"%b %d %H:%M:%S v06"}, value: "Mar 09 08:00:00 v06",
+			expected: time.Date(2025, 3, 9, 8, 0, 0, 0, time.UTC),
+		},
+		"two digits year": {
+			formats: []string{"%d/%m/%y"}, value: "10/10/24",
+			expected: time.Date(2024, 10, 10, 0, 0, 0, 0, time.UTC),
+		},
+		"unix": {
+			formats: []string{DateFormatUnix}, value: "1364481363",
+			expected: time.Date(2013, 3, 28, 14, 36, 3, 0, time.UTC),
+		},
+		"unix with decimals": {
+			formats: []string{DateFormatUnix}, value: "1364481363.243",
+			expected: time.Date(2013, 3, 28, 14, 36, 3, 243000000, time.UTC),
+		},
+		"unix with nanoseconds": {
+			formats: []string{DateFormatUnix}, value: "1364481363.123456789123",
+			expected: time.Date(2013, 3, 28, 14, 36, 3, 123456789, time.UTC),
+		},
+		"unix ms": {
+			formats: []string{DateFormatUnixMs}, value: "1728568536101",
+			expected: time.Date(2024, 10, 10, 13, 55, 36, 101000000, time.UTC),
+		},
+		"unix us": {
+			formats: []string{DateFormatUnixUs}, value: "1728568536101202",
+			expected: time.Date(2024, 10, 10, 13, 55, 36, 101202000, time.UTC),
+		},
+		"unix ns": {
+			formats: []string{DateFormatUnixNs}, value: "1728568536101202303",
+			expected: time.Date(2024, 10, 10, 13, 55, 36, 101202303, time.UTC),
+		},
+		"negative unix": {
+			formats: []string{DateFormatUnix}, value: "-1",
+			expected: time.Date(1969, 12, 31, 23, 59, 59, 0, time.UTC),
+		},
+		"negative unix with decimals": {
+			formats: []string{DateFormatUnix}, value: "-1.5",
+			expected: time.Date(1969, 12, 31, 23, 59, 58, 500000000, time.UTC),
+		},
+		"negative unix under one second": {
+			formats: []string{DateFormatUnix}, value: "-0.25",
+			expected: time.Date(1969, 12, 31, 23, 59, 59, 750000000, time.UTC),
+		},
+		"invalid epoch":          {formats: []string{DateFormatUnix}, value: "1364481363.2a", hasError: true},
+		"signed decimals":        {formats: []string{DateFormatUnix}, value: "1364481363.-2", hasError: true},
+		"double sign":            {formats: []string{DateFormatUnix}, value: "--1.5", hasError: true},
+		"missing integer part":   {formats: []string{DateFormatUnix}, value: ".5", hasError: true},
+		"epoch with decimals ms": {formats: []string{DateFormatUnixMs}, value: "1728568536.101", hasError: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			parser, err := NewDateParser(test.formats, test.location)
+			if err != nil {
+				t.Fatal(err)
+			}
+			testNow := test.now
+			if testNow.IsZero() {
+				testNow = now
+			}
+
+			date, err := parser.Parse(test.value, testNow)
+			if (err != nil) != test.hasError {
+				t.Fatalf("Parse(%q) error = %v, want error %t", test.value, err, test.hasError)
+			}
+			if !date.Equal(test.expected) {
+				t.Errorf("Parse(%q) = %s, want %s", test.value, date, test.expected)
+			}
+		})
+	}
+
+	if _, err := NewDateParser([]string{"%Y-%Q"}, nil); err == nil {
+		t.Errorf("invalid strftime format must be rejected")
+	}
+}
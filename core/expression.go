@@ -0,0 +1,791 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Expression is a boolean expression compiled from a small language without side effects :
+//   - literals : "string" or 'string', numbers with an optional unit (500, 1.5, 2s, 10KB), true, false, ["a", "b"]
+//   - fields : level, exception.class, field("field-with-special-chars"), exists(level)
+//   - comparison : ==, !=, <, <=, >, >= (a string compared to a number is converted to a number)
+//   - boolean logic : &&, ||, ! and parentheses
+//   - string operators : startsWith, endsWith, contains, in, matches, =~ and !~ (regex)
+//   - functions : int(), float(), lower(), upper(), trim(), len(), startsWith(), endsWith(), contains(), matches()
+//
+// Types are checked when the expression is compiled, so only conversions (e.g. int("abc")) can fail at evaluation.
+type Expression struct {
+	source string
+	root   exprNode
+}
+
+// ExpressionResolver returns the value of a field, and false if it does not exist. Missing fields are empty strings.
+type ExpressionResolver func(name string) (string, bool)
+
+// CompileExpression parse an expression, errors contain the column of the offending token.
+func CompileExpression(source string) (*Expression, error) {
+	tokens, err := lexExpression(source)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &exprParser{tokens: tokens}
+	root, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if token := parser.peek(); token.kind != exprTokenEOF {
+		return nil, exprErrorf(token.column, "unexpected %s", token)
+	}
+	if root.kind() != exprBool {
+		return nil, exprErrorf(1, "expression must be a condition, got a %s", root.kind())
+	}
+
+	return &Expression{source: source, root: root}, nil
+}
+
+// Evaluate returns true if expression is valid for fields returned by resolve.
+func (e *Expression) Evaluate(resolve ExpressionResolver) (bool, error) {
+	value, err := e.root.eval(resolve)
+	if err != nil {
+		return false, err
+	}
+	return value.(bool), nil
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+func exprErrorf(column int, format string, args ...interface{}) error {
+	return fmt.Errorf("column %d: %s", column, fmt.Sprintf(format, args...))
+}
+
+//
+// lexer
+//
+
+type exprTokenKind int
+
+const (
+	exprTokenEOF exprTokenKind = iota
+	exprTokenIdent
+	exprTokenString
+	exprTokenNumber
+	exprTokenOperator
+)
+
+type exprToken struct {
+	kind   exprTokenKind
+	text   string
+	number float64
+	column int
+}
+
+func (t exprToken) String() string {
+	switch t.kind {
+	case exprTokenEOF:
+		return "end of expression"
+	case exprTokenString:
+		return fmt.Sprintf("string %q", t.text)
+	default:
+		return fmt.Sprintf("\"%s\"", t.text)
+	}
+}
+
+var exprOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "!", "<", ">", "(", ")", "[", "]", ","}
+
+//nolint:gocyclo
+func lexExpression(source string) ([]exprToken, error) {
+	var tokens []exprToken
+	runes := []rune(source)
+	isIdent := func(c rune) bool { return c == '_' || c == '.' || unicode.IsLetter(c) || unicode.IsDigit(c) }
+
+	for i := 0; i < len(runes); {
+		c := runes[i]
+		column := i + 1
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(runes) && isIdent(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: exprTokenIdent, text: string(runes[start:i]), column: column})
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			// optional unit, e.g. "2s" or "10KB"
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || runes[i] == '%') {
+				i++
+			}
+			text := string(runes[start:i])
+			number, err := ParseNumber(text)
+			if err != nil {
+				return nil, exprErrorf(column, "invalid number: %s", err)
+			}
+			tokens = append(tokens, exprToken{kind: exprTokenNumber, text: text, number: number, column: column})
+		case c == '"' || c == '\'':
+			var builder strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != c; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					switch runes[i] {
+					case 'n':
+						builder.WriteRune('\n')
+					case 't':
+						builder.WriteRune('\t')
+					default:
+						builder.WriteRune(runes[i])
+					}
+					continue
+				}
+				builder.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, exprErrorf(column, "unterminated string")
+			}
+			i++
+			tokens = append(tokens, exprToken{kind: exprTokenString, text: builder.String(), column: column})
+		default:
+			operator := ""
+			for _, candidate := range exprOperators {
+				if strings.HasPrefix(string(runes[i:min(i+2, len(runes))]), candidate) {
+					operator = candidate
+					break
+				}
+			}
+			if operator == "" {
+				return nil, exprErrorf(column, "unexpected character %q", c)
+			}
+			i += len(operator)
+			tokens = append(tokens, exprToken{kind: exprTokenOperator, text: operator, column: column})
+		}
+	}
+
+	return append(tokens, exprToken{kind: exprTokenEOF, column: len(runes) + 1}), nil
+}
+
+//
+// parser
+//
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	token := p.tokens[p.pos]
+	if token.kind != exprTokenEOF {
+		p.pos++
+	}
+	return token
+}
+
+func (p *exprParser) isOperator(operators ...string) bool {
+	token := p.peek()
+	return token.kind == exprTokenOperator && SliceContains(operators, token.text)
+}
+
+func (p *exprParser) expect(operator string) error {
+	if token := p.next(); token.kind != exprTokenOperator || token.text != operator {
+		return exprErrorf(token.column, "expected \"%s\", got %s", operator, token)
+	}
+	return nil
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	return p.parseLogical("||", p.parseAnd)
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	return p.parseLogical("&&", p.parseComparison)
+}
+
+func (p *exprParser) parseLogical(operator string, parseOperand func() (exprNode, error)) (exprNode, error) {
+	left, err := parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator(operator) {
+		token := p.next()
+		right, err := parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if left.kind() != exprBool || right.kind() != exprBool {
+			return nil, exprErrorf(token.column, "operator \"%s\" expects conditions, got %s and %s", operator, left.kind(), right.kind())
+		}
+		left = &exprLogicalNode{or: operator == "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+//nolint:gocyclo
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	token := p.peek()
+	switch {
+	case p.isOperator("==", "!=", "<", "<=", ">", ">="):
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return newExprCompareNode(token, left, right)
+	case p.isOperator("=~", "!~") || (token.kind == exprTokenIdent && token.text == "matches"):
+		p.next()
+		if left.kind() != exprString {
+			return nil, exprErrorf(token.column, "operator \"%s\" expects a string, got %s", token.text, left.kind())
+		}
+		regex, err := p.parseRegex()
+		if err != nil {
+			return nil, err
+		}
+		return &exprRegexNode{operand: left, regex: regex, negate: token.text == "!~"}, nil
+	case token.kind == exprTokenIdent && SliceContains([]string{"startsWith", "endsWith", "contains"}, token.text):
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if left.kind() != exprString || right.kind() != exprString {
+			return nil, exprErrorf(token.column, "operator \"%s\" expects strings, got %s and %s", token.text, left.kind(), right.kind())
+		}
+		return &exprStringNode{operator: token.text, left: left, right: right}, nil
+	case token.kind == exprTokenIdent && token.text == "in":
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if right.kind() != exprList || left.kind() == exprBool || left.kind() == exprList {
+			return nil, exprErrorf(token.column, "operator \"in\" expects a string or a number and a list, got %s and %s", left.kind(), right.kind())
+		}
+		return &exprInNode{operand: left, list: right.(*exprLiteralNode).value.([]interface{})}, nil
+	}
+
+	return left, nil
+}
+
+func (p *exprParser) parseRegex() (*regexp.Regexp, error) {
+	token := p.next()
+	if token.kind != exprTokenString {
+		return nil, exprErrorf(token.column, "regex must be a string, got %s", token)
+	}
+	regex, err := regexp.Compile(token.text)
+	if err != nil {
+		return nil, exprErrorf(token.column, "invalid regex: %s", err)
+	}
+	return regex, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if !p.isOperator("!") {
+		return p.parsePrimary()
+	}
+
+	token := p.next()
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if operand.kind() != exprBool {
+		return nil, exprErrorf(token.column, "operator \"!\" expects a condition, got %s", operand.kind())
+	}
+	return &exprNotNode{operand: operand}, nil
+}
+
+//nolint:gocyclo
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	token := p.next()
+	switch token.kind {
+	case exprTokenString:
+		return &exprLiteralNode{value: token.text}, nil
+	case exprTokenNumber:
+		return &exprLiteralNode{value: token.number}, nil
+	case exprTokenIdent:
+		switch {
+		case token.text == "true" || token.text == "false":
+			return &exprLiteralNode{value: token.text == "true"}, nil
+		case p.isOperator("("):
+			return p.parseCall(token)
+		default:
+			return &exprFieldNode{name: token.text}, nil
+		}
+	case exprTokenOperator:
+		switch token.text {
+		case "(":
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return node, p.expect(")")
+		case "[":
+			return p.parseList()
+		}
+	}
+
+	return nil, exprErrorf(token.column, "unexpected %s", token)
+}
+
+// parseList parse a list of literals, e.g. ["GET", "POST"] or [500, 502].
+func (p *exprParser) parseList() (exprNode, error) {
+	var values []interface{}
+	for !p.isOperator("]") {
+		if len(values) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		token := p.next()
+		switch token.kind {
+		case exprTokenString:
+			values = append(values, token.text)
+		case exprTokenNumber:
+			values = append(values, token.number)
+		default:
+			return nil, exprErrorf(token.column, "list must contain strings or numbers, got %s", token)
+		}
+	}
+	p.next()
+	return &exprLiteralNode{value: values}, nil
+}
+
+//nolint:gocyclo
+func (p *exprParser) parseCall(name exprToken) (exprNode, error) {
+	p.next()
+
+	// functions with a field name or a regex as argument
+	switch name.text {
+	case "exists", "field":
+		token := p.next()
+		if token.kind != exprTokenString && (name.text == "field" || token.kind != exprTokenIdent) {
+			return nil, exprErrorf(token.column, "function \"%s\" expects a field name, got %s", name.text, token)
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		if name.text == "exists" {
+			return &exprExistsNode{name: token.text}, nil
+		}
+		return &exprFieldNode{name: token.text}, nil
+	case "matches":
+		operand, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if operand.kind() != exprString {
+			return nil, exprErrorf(name.column, "function \"matches\" expects a string, got %s", operand.kind())
+		}
+		if err = p.expect(","); err != nil {
+			return nil, err
+		}
+		regex, err := p.parseRegex()
+		if err != nil {
+			return nil, err
+		}
+		return &exprRegexNode{operand: operand, regex: regex}, p.expect(")")
+	}
+
+	var args []exprNode
+	for !p.isOperator(")") {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	p.next()
+
+	return newExprCallNode(name, args)
+}
+
+//
+// nodes
+//
+
+type exprType int
+
+const (
+	exprString exprType = iota
+	exprNumber
+	exprBool
+	exprList
+)
+
+func (t exprType) String() string {
+	return [...]string{"string", "number", "condition", "list"}[t]
+}
+
+type exprNode interface {
+	kind() exprType
+	eval(resolve ExpressionResolver) (interface{}, error)
+}
+
+type exprLiteralNode struct {
+	value interface{}
+}
+
+func (n *exprLiteralNode) kind() exprType {
+	switch n.value.(type) {
+	case string:
+		return exprString
+	case float64:
+		return exprNumber
+	case bool:
+		return exprBool
+	default:
+		return exprList
+	}
+}
+
+func (n *exprLiteralNode) eval(ExpressionResolver) (interface{}, error) {
+	return n.value, nil
+}
+
+type exprFieldNode struct {
+	name string
+}
+
+func (n *exprFieldNode) kind() exprType {
+	return exprString
+}
+
+func (n *exprFieldNode) eval(resolve ExpressionResolver) (interface{}, error) {
+	value, _ := resolve(n.name)
+	return value, nil
+}
+
+type exprExistsNode struct {
+	name string
+}
+
+func (n *exprExistsNode) kind() exprType {
+	return exprBool
+}
+
+func (n *exprExistsNode) eval(resolve ExpressionResolver) (interface{}, error) {
+	_, exists := resolve(n.name)
+	return exists, nil
+}
+
+type exprNotNode struct {
+	operand exprNode
+}
+
+func (n *exprNotNode) kind() exprType {
+	return exprBool
+}
+
+func (n *exprNotNode) eval(resolve ExpressionResolver) (interface{}, error) {
+	value, err := n.operand.eval(resolve)
+	if err != nil {
+		return nil, err
+	}
+	return !value.(bool), nil
+}
+
+type exprLogicalNode struct {
+	or    bool
+	left  exprNode
+	right exprNode
+}
+
+func (n *exprLogicalNode) kind() exprType {
+	return exprBool
+}
+
+func (n *exprLogicalNode) eval(resolve ExpressionResolver) (interface{}, error) {
+	left, err := n.left.eval(resolve)
+	if err != nil {
+		return nil, err
+	}
+	// short-circuit
+	if left.(bool) == n.or {
+		return n.or, nil
+	}
+	return n.right.eval(resolve)
+}
+
+type exprCompareNode struct {
+	operator string
+	left     exprNode
+	right    exprNode
+}
+
+func newExprCompareNode(operator exprToken, left, right exprNode) (exprNode, error) {
+	leftKind, rightKind := left.kind(), right.kind()
+	switch {
+	case leftKind == exprList || rightKind == exprList:
+		return nil, exprErrorf(operator.column, "operator \"%s\" can not compare lists, use \"in\"", operator.text)
+	case (leftKind == exprBool) != (rightKind == exprBool):
+		return nil, exprErrorf(operator.column, "operator \"%s\" can not compare %s and %s", operator.text, leftKind, rightKind)
+	case leftKind == exprBool && operator.text != "==" && operator.text != "!=":
+		return nil, exprErrorf(operator.column, "operator \"%s\" can not compare conditions", operator.text)
+	}
+	return &exprCompareNode{operator: operator.text, left: left, right: right}, nil
+}
+
+func (n *exprCompareNode) kind() exprType {
+	return exprBool
+}
+
+func (n *exprCompareNode) eval(resolve ExpressionResolver) (interface{}, error) {
+	left, err := n.left.eval(resolve)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(resolve)
+	if err != nil {
+		return nil, err
+	}
+
+	if l, ok := left.(bool); ok {
+		if l == right.(bool) {
+			return n.operator == "==", nil
+		}
+		return n.operator == "!=", nil
+	}
+
+	var comparison int
+	var ok bool
+	l, lok := left.(string)
+	r, rok := right.(string)
+	if lok && rok {
+		comparison = strings.Compare(l, r)
+	} else if comparison, ok = compareExprNumbers(left, right); !ok {
+		// a value which is not a number is only different from a number
+		return n.operator == "!=", nil
+	}
+
+	switch n.operator {
+	case "==":
+		return comparison == 0, nil
+	case "!=":
+		return comparison != 0, nil
+	case "<":
+		return comparison < 0, nil
+	case "<=":
+		return comparison <= 0, nil
+	case ">":
+		return comparison > 0, nil
+	default:
+		return comparison >= 0, nil
+	}
+}
+
+func toExprNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		number, err := ParseNumber(v)
+		return number, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func compareExprNumbers(left, right interface{}) (int, bool) {
+	l, lok := toExprNumber(left)
+	r, rok := toExprNumber(right)
+	switch {
+	case !lok || !rok:
+		return 0, false
+	case l < r:
+		return -1, true
+	case l > r:
+		return 1, true
+	default:
+		return 0, true
+	}
+}
+
+type exprInNode struct {
+	operand exprNode
+	list    []interface{}
+}
+
+func (n *exprInNode) kind() exprType {
+	return exprBool
+}
+
+func (n *exprInNode) eval(resolve ExpressionResolver) (interface{}, error) {
+	value, err := n.operand.eval(resolve)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range n.list {
+		l, lok := value.(string)
+		r, rok := item.(string)
+		if lok && rok {
+			if l == r {
+				return true, nil
+			}
+			continue
+		}
+		if comparison, ok := compareExprNumbers(value, item); ok && comparison == 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type exprRegexNode struct {
+	operand exprNode
+	regex   *regexp.Regexp
+	negate  bool
+}
+
+func (n *exprRegexNode) kind() exprType {
+	return exprBool
+}
+
+func (n *exprRegexNode) eval(resolve ExpressionResolver) (interface{}, error) {
+	value, err := n.operand.eval(resolve)
+	if err != nil {
+		return nil, err
+	}
+	return n.regex.MatchString(value.(string)) != n.negate, nil
+}
+
+type exprStringNode struct {
+	operator string
+	left     exprNode
+	right    exprNode
+}
+
+func (n *exprStringNode) kind() exprType {
+	return exprBool
+}
+
+func (n *exprStringNode) eval(resolve ExpressionResolver) (interface{}, error) {
+	left, err := n.left.eval(resolve)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(resolve)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.operator {
+	case "startsWith":
+		return strings.HasPrefix(left.(string), right.(string)), nil
+	case "endsWith":
+		return strings.HasSuffix(left.(string), right.(string)), nil
+	default:
+		return strings.Contains(left.(string), right.(string)), nil
+	}
+}
+
+type exprFunction struct {
+	args   []exprType
+	result exprType
+	// cast accepts numbers as well as strings
+	cast bool
+	call func(args []interface{}) (interface{}, error)
+}
+
+var exprFunctions = map[string]exprFunction{
+	"int": {args: []exprType{exprString}, result: exprNumber, cast: true, call: func(args []interface{}) (interface{}, error) {
+		number, err := exprCastNumber(args[0])
+		return math.Trunc(number), err
+	}},
+	"float": {args: []exprType{exprString}, result: exprNumber, cast: true, call: func(args []interface{}) (interface{}, error) {
+		return exprCastNumber(args[0])
+	}},
+	"lower": {args: []exprType{exprString}, result: exprString, call: func(args []interface{}) (interface{}, error) {
+		return strings.ToLower(args[0].(string)), nil
+	}},
+	"upper": {args: []exprType{exprString}, result: exprString, call: func(args []interface{}) (interface{}, error) {
+		return strings.ToUpper(args[0].(string)), nil
+	}},
+	"trim": {args: []exprType{exprString}, result: exprString, call: func(args []interface{}) (interface{}, error) {
+		return strings.TrimSpace(args[0].(string)), nil
+	}},
+	"len": {args: []exprType{exprString}, result: exprNumber, call: func(args []interface{}) (interface{}, error) {
+		return float64(len([]rune(args[0].(string)))), nil
+	}},
+}
+
+func exprCastNumber(value interface{}) (float64, error) {
+	number, ok := toExprNumber(value)
+	if !ok {
+		return 0, fmt.Errorf("\"%v\" is not a number", value)
+	}
+	return number, nil
+}
+
+type exprCallNode struct {
+	name     string
+	function exprFunction
+	args     []exprNode
+}
+
+func newExprCallNode(name exprToken, args []exprNode) (exprNode, error) {
+	// string functions are aliases of string operators, e.g. startsWith(path, "/api")
+	if SliceContains([]string{"startsWith", "endsWith", "contains"}, name.text) {
+		if len(args) != 2 || args[0].kind() != exprString || args[1].kind() != exprString {
+			return nil, exprErrorf(name.column, "function \"%s\" expects 2 strings", name.text)
+		}
+		return &exprStringNode{operator: name.text, left: args[0], right: args[1]}, nil
+	}
+
+	function, ok := exprFunctions[name.text]
+	if !ok {
+		return nil, exprErrorf(name.column, "unknown function \"%s\"", name.text)
+	}
+	if len(args) != len(function.args) {
+		return nil, exprErrorf(name.column, "function \"%s\" expects %d argument(s), got %d", name.text, len(function.args), len(args))
+	}
+	for i, arg := range args {
+		expected := function.args[i]
+		if arg.kind() != expected && !(function.cast && arg.kind() == exprNumber) {
+			return nil, exprErrorf(name.column, "function \"%s\" expects a %s, got %s", name.text, expected, arg.kind())
+		}
+	}
+	return &exprCallNode{name: name.text, function: function, args: args}, nil
+}
+
+func (n *exprCallNode) kind() exprType {
+	return n.function.result
+}
+
+func (n *exprCallNode) eval(resolve ExpressionResolver) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		value, err := arg.eval(resolve)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+	value, err := n.function.call(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %w", n.name, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpression(t *testing.T) {
+	fields := map[string]string{
+		"level":           "error",
+		"status":          "502",
+		"path":            "/api/orders",
+		"duration":        "1.5s",
+		"size":            "10KB",
+		"exception.class": "KeyError",
+		"user-agent":      "curl/8.4.0",
+		"empty":           "",
+	}
+	resolve := func(name string) (string, bool) {
+		value, ok := fields[name]
+		return value, ok
+	}
+
+	tests := map[string]struct {
+		source   string
+		expected bool
+		hasError bool
+	}{
+		// precedence and parentheses
+		"and before or":              {source: `true || false && false`, expected: true},
+		"parentheses before and":     {source: `(true || false) && false`, expected: false},
+		"not before and":             {source: `!false && false`, expected: false},
+		"not on parentheses":         {source: `!(false && false)`, expected: true},
+		"double not":                 {source: `!!true`, expected: true},
+		"comparison before and":      {source: `level == "error" && status >= 500`, expected: true},
+		"nested parentheses":         {source: `((level == "info") || (status == 502 && !(path startsWith "/health")))`, expected: true},
+		"or short-circuits errors":   {source: `true || int(level) > 0`, expected: true},
+		"and short-circuits errors":  {source: `false && int(level) > 0`, expected: false},
+		"condition compared":         {source: `(status > 500) == true`, expected: true},
+		"condition compared to cond": {source: `(status > 500) != (level == "error")`, expected: false},
+
+		// string and number comparison
+		"string equality":              {source: `level == "error"`, expected: true},
+		"string inequality":            {source: `level != 'error'`, expected: false},
+		"string ordering":              {source: `level < "warning"`, expected: true},
+		"numeric string to number":     {source: `status > 500`, expected: true},
+		"numeric strings as strings":   {source: `status < "6"`, expected: true},
+		"number to numeric string":     {source: `500 < status`, expected: true},
+		"duration units":               {source: `duration >= 1500ms`, expected: true},
+		"size units":                   {source: `size == 10240`, expected: true},
+		"text is not a number":         {source: `level == 0`, expected: false},
+		"text differs from a number":   {source: `level != 0`, expected: true},
+		"text is not ordered":          {source: `level > 0`, expected: false},
+		"cast":                         {source: `int(status) == 502`, expected: true},
+		"cast truncates":               {source: `int(duration) == 1 && float(duration) == 1.5`, expected: true},
+		"in strings":                   {source: `level in ["warning", "error"]`, expected: true},
+		"in numbers":                   {source: `status in [500, 502, 503]`, expected: true},
+		"not in list":                  {source: `level in ["info"]`, expected: false},
+		"string operators":             {source: `path startsWith "/api" && path endsWith "orders" && path contains "/ord"`, expected: true},
+		"string functions":             {source: `startsWith(lower(upper(path)), "/api") && len(trim(" ab ")) == 2`, expected: true},
+		"regex":                        {source: `path =~ "^/api/[a-z]+$" && level !~ "^warn"`, expected: true},
+		"regex function":               {source: `matches(exception.class, "Error$") && path matches "orders"`, expected: true},
+		"escaped string":               {source: `"a\"b" == 'a"b'`, expected: true},
+		"special field name":           {source: `field("user-agent") startsWith "curl/"`, expected: true},
+		"existing empty field":         {source: `exists(empty) && exists("empty")`, expected: true},
+		"unknown field does not exist": {source: `exists(missing)`, expected: false},
+		"unknown field is empty":       {source: `missing == ""`, expected: true},
+		"unknown field is no number":   {source: `missing < 1`, expected: false},
+		"unknown field regex":          {source: `missing =~ "^$"`, expected: true},
+
+		// evaluation errors
+		"invalid cast":          {source: `int(level) > 0`, hasError: true},
+		"invalid cast of field": {source: `float(missing) == 0`, hasError: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			expression, err := CompileExpression(test.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := expression.Evaluate(resolve)
+			if (err != nil) != test.hasError {
+				t.Fatalf("Evaluate(%s) error = %v, want error %t", test.source, err, test.hasError)
+			}
+			if result != test.expected {
+				t.Errorf("Evaluate(%s) = %t, want %t", test.source, result, test.expected)
+			}
+		})
+	}
+}
+
+func TestCompileExpressionErrors(t *testing.T) {
+	tests := map[string]struct {
+		source string
+		err    string
+	}{
+		"empty":                   {source: ``, err: "column 1: unexpected end of expression"},
+		"not a condition":         {source: `level`, err: "column 1: expression must be a condition, got a string"},
+		"unexpected character":    {source: `status & 1`, err: `column 8: unexpected character '&'`},
+		"division is unsupported": {source: `status / 0 > 1`, err: `column 8: unexpected character '/'`},
+		"unterminated string":     {source: `level == "error`, err: "column 10: unterminated string"},
+		"invalid number":          {source: `duration > 10m`, err: "column 12: invalid number"},
+		"missing parenthesis":     {source: `(level == "error"`, err: `column 18: expected ")", got end of expression`},
+		"extra parenthesis":       {source: `level == "error")`, err: `column 17: unexpected ")"`},
+		"missing operand":         {source: `level == "error" &&`, err: "column 20: unexpected end of expression"},
+		"and on strings":          {source: `level && true`, err: `column 7: operator "&&" expects conditions, got string and condition`},
+		"not on string":           {source: `!level`, err: `column 1: operator "!" expects a condition, got string`},
+		"compare condition":       {source: `true == level`, err: `column 6: operator "==" can not compare condition and string`},
+		"order conditions":        {source: `true < false`, err: `column 6: operator "<" can not compare conditions`},
+		"compare list":            {source: `level == ["a"]`, err: `column 7: operator "==" can not compare lists, use "in"`},
+		"in without list":         {source: `level in "error"`, err: `column 7: operator "in" expects a string or a number and a list`},
+		"list of fields":          {source: `level in [level]`, err: `column 11: list must contain strings or numbers, got "level"`},
+		"regex on number":         {source: `1 =~ "a"`, err: `column 3: operator "=~" expects a string, got number`},
+		"regex from field":        {source: `level =~ path`, err: `column 10: regex must be a string, got "path"`},
+		"invalid regex":           {source: `level =~ "(" `, err: "column 10: invalid regex"},
+		"contains number":         {source: `path contains 1`, err: `column 6: operator "contains" expects strings, got string and number`},
+		"unknown function":        {source: `size(path) > 1`, err: `column 1: unknown function "size"`},
+		"argument count":          {source: `lower(level, path) == ""`, err: `column 1: function "lower" expects 1 argument(s), got 2`},
+		"argument type":           {source: `len(1) == 1`, err: `column 1: function "len" expects a string, got number`},
+		"field without name":      {source: `field(level) == ""`, err: `column 7: function "field" expects a field name, got "level"`},
+		"position after unicode":  {source: `"é" == level ||`, err: "column 16: unexpected end of expression"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := CompileExpression(test.source)
+			if err == nil {
+				t.Fatalf("CompileExpression(%s) must be rejected", test.source)
+			}
+			if !strings.HasPrefix(err.Error(), test.err) {
+				t.Errorf("CompileExpression(%s) error = %q, want %q", test.source, err, test.err)
+			}
+		})
+	}
+}
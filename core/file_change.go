@@ -0,0 +1,60 @@
+package core
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// FileChangeDetector detect when a file is replaced or modified on disk.
+// File is checked at most once per interval to keep calls cheap on hot paths.
+type FileChangeDetector struct {
+	mu        sync.Mutex
+	path      string
+	interval  time.Duration
+	lastCheck time.Time
+	modTime   time.Time
+	size      int64
+}
+
+func NewFileChangeDetector(path string, interval time.Duration) *FileChangeDetector {
+	detector := &FileChangeDetector{
+		path:     path,
+		interval: interval,
+	}
+	detector.modTime, detector.size = detector.stat()
+	detector.lastCheck = time.Now()
+
+	return detector
+}
+
+// Changed returns true if file changed since the last call which returned true.
+func (detector *FileChangeDetector) Changed() bool {
+	detector.mu.Lock()
+	defer detector.mu.Unlock()
+
+	if time.Since(detector.lastCheck) < detector.interval {
+		return false
+	}
+	detector.lastCheck = time.Now()
+
+	modTime, size := detector.stat()
+	// file missing (e.g. during replacement) : wait for the new file
+	if modTime.IsZero() {
+		return false
+	}
+	if modTime.Equal(detector.modTime) && size == detector.size {
+		return false
+	}
+	detector.modTime, detector.size = modTime, size
+
+	return true
+}
+
+func (detector *FileChangeDetector) stat() (time.Time, int64) {
+	info, err := os.Stat(detector.path)
+	if err != nil {
+		return time.Time{}, 0
+	}
+	return info.ModTime(), info.Size()
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// CompileGlob convert a wildcard pattern to an anchored regexp : "*" matches any characters (including "/"),
+// "?" matches one character and "[abc]", "[a-z]" or "[!abc]" match a class of characters.
+func CompileGlob(pattern string, caseSensitive bool) (*regexp.Regexp, error) {
+	var builder strings.Builder
+	if !caseSensitive {
+		builder.WriteString("(?i)")
+	}
+	builder.WriteString("^")
+
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; c {
+		case '*':
+			builder.WriteString(".*")
+		case '?':
+			builder.WriteString(".")
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+			}
+			builder.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case '[':
+			end := i + 1
+			if end < len(runes) && runes[end] == '!' {
+				end++
+			}
+			if end < len(runes) && runes[end] == ']' {
+				end++
+			}
+			for end < len(runes) && runes[end] != ']' {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("\"%s\" is not a valid glob (unclosed \"[\")", pattern)
+			}
+			class := string(runes[i+1 : end])
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			builder.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i = end
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	builder.WriteString("$")
+
+	r, err := regexp.Compile(builder.String())
+	if err != nil {
+		return nil, fmt.Errorf("\"%s\" is not a valid glob (%w)", pattern, err)
+	}
+	return r, nil
+}
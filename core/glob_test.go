@@ -0,0 +1,60 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestCompileGlob(t *testing.T) {
+	tests := map[string]struct {
+		pattern       string
+		caseSensitive bool
+		matches       []string
+		notMatches    []string
+	}{
+		"star": {pattern: "/api/*", matches: []string{"/api/", "/api/orders", "/api/orders/5"}, notMatches: []string{"/apis", "/health"}},
+		"star in middle": {
+			pattern:    "*.example.com",
+			matches:    []string{"shop.example.com", "a.b.example.com"},
+			notMatches: []string{"example.com", "shop.example.com.evil"},
+		},
+		"question mark":    {pattern: "v?", matches: []string{"v1", "v2"}, notMatches: []string{"v", "v10"}},
+		"class":            {pattern: "5[0-9][02]", matches: []string{"500", "502", "592"}, notMatches: []string{"501", "404", "5000"}},
+		"negated class":    {pattern: "[!a-c]*", matches: []string{"delete", "-"}, notMatches: []string{"add", "cancel", ""}},
+		"bracket in class": {pattern: "[]x]", matches: []string{"]", "x"}, notMatches: []string{"y"}},
+		"escaped wildcard": {pattern: `what\?`, matches: []string{"what?"}, notMatches: []string{"whatx"}},
+		"regex characters": {pattern: "(a+b).c$", matches: []string{"(a+b).c$"}, notMatches: []string{"aab-c", "ab.c"}},
+		"case insensitive": {pattern: "*ERROR*", matches: []string{"an error occurred", "ERROR"}, notMatches: []string{"warning"}},
+		"case sensitive": {
+			pattern: "*ERROR*", caseSensitive: true, matches: []string{"ERROR: oops"}, notMatches: []string{"an error occurred"},
+		},
+		"unicode":       {pattern: "caf?", matches: []string{"café"}, notMatches: []string{"cafés"}},
+		"empty pattern": {pattern: "", matches: []string{""}, notMatches: []string{"a"}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			glob, err := CompileGlob(test.pattern, test.caseSensitive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, value := range test.matches {
+				if !glob.MatchString(value) {
+					t.Errorf("glob %q must match %q", test.pattern, value)
+				}
+			}
+			for _, value := range test.notMatches {
+				if glob.MatchString(value) {
+					t.Errorf("glob %q must not match %q", test.pattern, value)
+				}
+			}
+		})
+	}
+}
+
+func TestCompileGlobInvalid(t *testing.T) {
+	for _, pattern := range []string{"[abc", "[!", "[z-a]"} {
+		if _, err := CompileGlob(pattern, false); err == nil {
+			t.Errorf("glob %q must be rejected", pattern)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package core
 
+import "sort"
+
 func SliceContains[T comparable](arr []T, x T) bool {
 	for _, v := range arr {
 		if v == x {
@@ -16,3 +18,12 @@ func IsDecimal(a float64) bool {
 		return false
 	}
 }
+
+func SortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
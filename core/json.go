@@ -0,0 +1,85 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// JSONFlattenLimits bound the size of a flattened JSON document.
+type JSONFlattenLimits struct {
+	MaxDepth int
+	MaxKeys  int
+}
+
+// JSONValueToString convert a decoded json value to its string representation.
+func JSONValueToString(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+
+	switch reflect.TypeOf(value).Kind() {
+	case reflect.String:
+		return value.(string)
+	case reflect.Float64:
+		if IsDecimal(value.(float64)) {
+			return fmt.Sprintf("%d", int64(value.(float64)))
+		}
+		return fmt.Sprintf("%f", value)
+	case reflect.Int:
+		return fmt.Sprintf("%d", value.(int))
+	case reflect.Map, reflect.Slice:
+		content, _ := json.Marshal(value)
+		return string(content)
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+// FlattenJSON flatten a decoded json value into dotted keys (e.g. "context.user.id", "tags.0").
+// Values deeper than limits.MaxDepth are kept as json strings and no more than limits.MaxKeys keys
+// are written into fields. It returns the number of keys written.
+func FlattenJSON(fields map[string]string, prefix string, value interface{}, limits JSONFlattenLimits) int {
+	written := 0
+	flattenJSONValue(fields, prefix, value, 0, limits, &written)
+	return written
+}
+
+func flattenJSONValue(fields map[string]string, key string, value interface{}, depth int, limits JSONFlattenLimits, written *int) {
+	if limits.MaxKeys > 0 && *written >= limits.MaxKeys {
+		return
+	}
+
+	canDescend := limits.MaxDepth <= 0 || depth < limits.MaxDepth
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if canDescend && len(v) > 0 {
+			// sort keys to keep the same fields when the keys limit is reached
+			for _, k := range SortedKeys(v) {
+				flattenJSONValue(fields, joinJSONKey(key, k), v[k], depth+1, limits, written)
+			}
+			return
+		}
+	case []interface{}:
+		if canDescend && len(v) > 0 {
+			for i, item := range v {
+				flattenJSONValue(fields, joinJSONKey(key, strconv.Itoa(i)), item, depth+1, limits, written)
+			}
+			return
+		}
+	}
+
+	if key == "" {
+		return
+	}
+	fields[key] = JSONValueToString(value)
+	*written++
+}
+
+func joinJSONKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
@@ -0,0 +1,246 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JSONPath is a compiled json selector supporting a subset of JSONPath :
+//   - "a.b.c" or "$.a.b.c" : object keys
+//   - "errors[0].code" : array index (negative index starts from the end)
+//   - "context.*" or "items[*].id" : wildcard on object keys or array items
+//   - "[\"http.status\"]" or "['http.status']" : quoted key which may contain dots
+//   - "message | msg | log" : fallbacks, the first alternative with a non-empty value is used
+type JSONPath struct {
+	expression   string
+	alternatives []jsonPathAlternative
+}
+
+// JSONPathMatch is a value selected by a JSONPath.
+// Keys contains the object keys and array indexes matched by wildcards.
+type JSONPathMatch struct {
+	Keys  []string
+	Value interface{}
+}
+
+type jsonPathAlternative struct {
+	raw   string
+	steps []jsonPathStep
+}
+
+type jsonPathStepKind int
+
+const (
+	jsonPathStepKey jsonPathStepKind = iota
+	jsonPathStepIndex
+	jsonPathStepWildcard
+)
+
+type jsonPathStep struct {
+	kind  jsonPathStepKind
+	key   string
+	index int
+}
+
+// CompileJSONPath parse a json selector expression.
+func CompileJSONPath(expression string) (*JSONPath, error) {
+	path := &JSONPath{expression: expression}
+
+	parts, err := splitJSONPathAlternatives(expression)
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range parts {
+		raw := strings.TrimSpace(part)
+		if raw == "" {
+			return nil, fmt.Errorf("invalid selector \"%s\": empty alternative", expression)
+		}
+		steps, err := parseJSONPathSteps(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector \"%s\": %w", expression, err)
+		}
+		path.alternatives = append(path.alternatives, jsonPathAlternative{raw: raw, steps: steps})
+	}
+
+	return path, nil
+}
+
+// String returns the source expression.
+func (path *JSONPath) String() string {
+	return path.expression
+}
+
+// HasWildcard returns true if one of the alternatives may return several values.
+func (path *JSONPath) HasWildcard() bool {
+	for _, alternative := range path.alternatives {
+		for _, step := range alternative.steps {
+			if step.kind == jsonPathStepWildcard {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Select returns the values matched by the first alternative returning a non-empty value.
+// Traversal never panics : a step applied to a value of an unexpected type simply does not match.
+func (path *JSONPath) Select(data interface{}) []JSONPathMatch {
+	for _, alternative := range path.alternatives {
+		// a root key named like the whole expression has priority (e.g. "http.status" flat key)
+		if root, ok := data.(map[string]interface{}); ok {
+			if value, ok := root[alternative.raw]; ok && !isEmptyJSONValue(value) {
+				return []JSONPathMatch{{Value: value}}
+			}
+		}
+
+		matches := selectJSONPathSteps(data, alternative.steps, nil, nil)
+		for _, match := range matches {
+			if !isEmptyJSONValue(match.Value) {
+				return matches
+			}
+		}
+	}
+
+	return nil
+}
+
+func selectJSONPathSteps(data interface{}, steps []jsonPathStep, keys []string, matches []JSONPathMatch) []JSONPathMatch {
+	if len(steps) == 0 {
+		return append(matches, JSONPathMatch{Keys: append([]string{}, keys...), Value: data})
+	}
+
+	step, next := steps[0], steps[1:]
+	switch step.kind {
+	case jsonPathStepKey:
+		if obj, ok := data.(map[string]interface{}); ok {
+			if value, ok := obj[step.key]; ok {
+				return selectJSONPathSteps(value, next, keys, matches)
+			}
+		}
+	case jsonPathStepIndex:
+		if arr, ok := data.([]interface{}); ok {
+			index := step.index
+			if index < 0 {
+				index += len(arr)
+			}
+			if index >= 0 && index < len(arr) {
+				return selectJSONPathSteps(arr[index], next, keys, matches)
+			}
+		}
+	case jsonPathStepWildcard:
+		switch value := data.(type) {
+		case map[string]interface{}:
+			for _, key := range SortedKeys(value) {
+				matches = selectJSONPathSteps(value[key], next, append(keys, key), matches)
+			}
+		case []interface{}:
+			for i, item := range value {
+				matches = selectJSONPathSteps(item, next, append(keys, strconv.Itoa(i)), matches)
+			}
+		}
+	}
+
+	return matches
+}
+
+func isEmptyJSONValue(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	if str, ok := value.(string); ok && str == "" {
+		return true
+	}
+	return false
+}
+
+func splitJSONPathAlternatives(expression string) ([]string, error) {
+	parts := []string{}
+	var quote rune
+	start := 0
+	for i, c := range expression {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			continue
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '|':
+			parts = append(parts, expression[start:i])
+			start = i + 1
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("invalid selector \"%s\": unterminated quote", expression)
+	}
+
+	return append(parts, expression[start:]), nil
+}
+
+//nolint:gocyclo
+func parseJSONPathSteps(raw string) ([]jsonPathStep, error) {
+	steps := []jsonPathStep{}
+	expr := raw
+	if strings.HasPrefix(expr, "$") {
+		expr = expr[1:]
+	}
+
+	pos := 0
+	for pos < len(expr) {
+		switch c := expr[pos]; {
+		case c == '.':
+			pos++
+			if pos >= len(expr) || expr[pos] == '.' || expr[pos] == '[' {
+				return nil, fmt.Errorf("empty key at position %d", pos)
+			}
+		case c == '[':
+			end := strings.IndexByte(expr[pos:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("missing \"]\" at position %d", pos)
+			}
+			content := strings.TrimSpace(expr[pos+1 : pos+end])
+			if content != "" && (content[0] == '"' || content[0] == '\'') {
+				// quoted key : the closing bracket must follow the closing quote
+				open := pos + 1 + strings.IndexByte(expr[pos+1:], content[0])
+				closing := strings.IndexByte(expr[open+1:], content[0])
+				if closing < 0 {
+					return nil, fmt.Errorf("unterminated quote at position %d", open)
+				}
+				key := expr[open+1 : open+1+closing]
+				rest := strings.TrimLeft(expr[open+1+closing+1:], " ")
+				if !strings.HasPrefix(rest, "]") {
+					return nil, fmt.Errorf("missing \"]\" after quoted key \"%s\"", key)
+				}
+				steps = append(steps, jsonPathStep{kind: jsonPathStepKey, key: key})
+				pos = len(expr) - len(rest) + 1
+				continue
+			}
+			switch {
+			case content == "*":
+				steps = append(steps, jsonPathStep{kind: jsonPathStepWildcard})
+			default:
+				index, err := strconv.Atoi(content)
+				if err != nil {
+					return nil, fmt.Errorf("invalid array index \"%s\" at position %d", content, pos+1)
+				}
+				steps = append(steps, jsonPathStep{kind: jsonPathStepIndex, index: index})
+			}
+			pos += end + 1
+		default:
+			end := strings.IndexAny(expr[pos:], ".[")
+			if end < 0 {
+				end = len(expr) - pos
+			}
+			key := expr[pos : pos+end]
+			if key == "*" {
+				steps = append(steps, jsonPathStep{kind: jsonPathStepWildcard})
+			} else {
+				steps = append(steps, jsonPathStep{kind: jsonPathStepKey, key: key})
+			}
+			pos += end
+		}
+	}
+
+	return steps, nil
+}
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"strings"
+)
+
+// ParseKeyValues parse a list of key/value pairs, e.g. `user=bob msg="hello world" debug`.
+// Values may be quoted with double quotes (backslash escapes quotes), keys without value are set to "true".
+// It is used for logfmt (pairSeparator " ", keyValueSeparator "=") and custom key/value formats.
+func ParseKeyValues(text, pairSeparator, keyValueSeparator string) map[string]string {
+	values := map[string]string{}
+
+	rest := text
+	for rest != "" {
+		rest = strings.TrimLeft(rest, pairSeparator+" ")
+		if rest == "" {
+			break
+		}
+
+		// key
+		keyEnd := len(rest)
+		if i := strings.Index(rest, keyValueSeparator); i >= 0 {
+			keyEnd = i
+		}
+		if i := strings.Index(rest, pairSeparator); i >= 0 && i < keyEnd {
+			// key without value
+			values[strings.TrimSpace(rest[:i])] = "true"
+			rest = rest[i+len(pairSeparator):]
+			continue
+		}
+		key := strings.TrimSpace(rest[:keyEnd])
+		if keyEnd == len(rest) {
+			if key != "" {
+				values[key] = "true"
+			}
+			break
+		}
+		rest = rest[keyEnd+len(keyValueSeparator):]
+
+		// value
+		var value string
+		value, rest = readKeyValueValue(rest, pairSeparator)
+		if key != "" {
+			values[key] = value
+		}
+	}
+
+	return values
+}
+
+func readKeyValueValue(text, pairSeparator string) (value, rest string) {
+	if !strings.HasPrefix(text, `"`) {
+		end := strings.Index(text, pairSeparator)
+		if end < 0 {
+			return text, ""
+		}
+		return text[:end], text[end+len(pairSeparator):]
+	}
+
+	var builder strings.Builder
+	for i := 1; i < len(text); i++ {
+		switch {
+		case text[i] == '\\' && i+1 < len(text):
+			i++
+			builder.WriteByte(text[i])
+		case text[i] == '"':
+			rest = text[i+1:]
+			if end := strings.Index(rest, pairSeparator); end >= 0 {
+				rest = rest[end+len(pairSeparator):]
+			} else {
+				rest = ""
+			}
+			return builder.String(), rest
+		default:
+			builder.WriteByte(text[i])
+		}
+	}
+
+	// unterminated quote : keep the remaining text as value
+	return builder.String(), ""
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKeyValues(t *testing.T) {
+	tests := map[string]struct {
+		text              string
+		pairSeparator     string
+		keyValueSeparator string
+		expected          map[string]string
+	}{
+		"logfmt": {
+			text: `level=info user=bob msg="hello world" duration=1.5s`, pairSeparator: " ", keyValueSeparator: "=",
+			expected: map[string]string{"level": "info", "user": "bob", "msg": "hello world", "duration": "1.5s"},
+		},
+		"escaped quotes": {
+			text: `msg="say \"hi\"" path="C:\\tmp"`, pairSeparator: " ", keyValueSeparator: "=",
+			expected: map[string]string{"msg": `say "hi"`, "path": `C:\tmp`},
+		},
+		"keys without value": {
+			text: `debug user=bob verbose`, pairSeparator: " ", keyValueSeparator: "=",
+			expected: map[string]string{"debug": "true", "user": "bob", "verbose": "true"},
+		},
+		"empty values": {
+			text: `user= msg="" status=200`, pairSeparator: " ", keyValueSeparator: "=",
+			expected: map[string]string{"user": "", "msg": "", "status": "200"},
+		},
+		"extra spaces": {
+			text: `  user=bob    status=200  `, pairSeparator: " ", keyValueSeparator: "=",
+			expected: map[string]string{"user": "bob", "status": "200"},
+		},
+		"value containing separator": {
+			text: `query=a=b user=bob`, pairSeparator: " ", keyValueSeparator: "=",
+			expected: map[string]string{"query": "a=b", "user": "bob"},
+		},
+		"custom separators": {
+			text: `user:bob; status:200;msg:"a; b"`, pairSeparator: ";", keyValueSeparator: ":",
+			expected: map[string]string{"user": "bob", "status": "200", "msg": "a; b"},
+		},
+		"multi-character separators": {
+			text: `user=>bob, status=>200`, pairSeparator: ", ", keyValueSeparator: "=>",
+			expected: map[string]string{"user": "bob", "status": "200"},
+		},
+		"unterminated quote": {
+			text: `user=bob msg="hello world`, pairSeparator: " ", keyValueSeparator: "=",
+			expected: map[string]string{"user": "bob", "msg": "hello world"},
+		},
+		"empty key": {
+			text: `=orphan user=bob`, pairSeparator: " ", keyValueSeparator: "=",
+			expected: map[string]string{"user": "bob"},
+		},
+		"empty text": {text: "", pairSeparator: " ", keyValueSeparator: "=", expected: map[string]string{}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			values := ParseKeyValues(test.text, test.pairSeparator, test.keyValueSeparator)
+			if !reflect.DeepEqual(values, test.expected) {
+				t.Errorf("ParseKeyValues(%q) = %v, want %v", test.text, values, test.expected)
+			}
+		})
+	}
+}
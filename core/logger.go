@@ -4,6 +4,8 @@ import (
 	"fmt"
 	baseLog "log"
 	"runtime/debug"
+	"sync"
+	"time"
 )
 
 var Logger = &LoggerStruct{
@@ -57,3 +59,42 @@ func (logger *LoggerStruct) Debugf(prefix, format string, v ...interface{}) {
 		baseLog.Print(str)
 	}
 }
+
+// LogRateLimiter limits logs to one per key and interval, suppressed logs are counted
+// and reported with the next emitted log.
+type LogRateLimiter struct {
+	mu         sync.Mutex
+	logger     *LoggerStruct
+	interval   time.Duration
+	lastLogs   map[string]time.Time
+	suppressed map[string]int
+}
+
+func NewLogRateLimiter(logger *LoggerStruct, interval time.Duration) *LogRateLimiter {
+	return &LogRateLimiter{
+		logger:     logger,
+		interval:   interval,
+		lastLogs:   map[string]time.Time{},
+		suppressed: map[string]int{},
+	}
+}
+
+// Errorf print an error log, unless another log with the same key was printed during interval.
+func (limiter *LogRateLimiter) Errorf(key, prefix, format string, v ...interface{}) {
+	limiter.mu.Lock()
+	if time.Since(limiter.lastLogs[key]) < limiter.interval {
+		limiter.suppressed[key]++
+		limiter.mu.Unlock()
+		return
+	}
+	suppressed := limiter.suppressed[key]
+	limiter.lastLogs[key] = time.Now()
+	limiter.suppressed[key] = 0
+	limiter.mu.Unlock()
+
+	message := fmt.Sprintf(format, v...)
+	if suppressed > 0 {
+		message = fmt.Sprintf("%s (%d similar error(s) suppressed)", message, suppressed)
+	}
+	limiter.logger.Errorf(prefix, "%s", message)
+}
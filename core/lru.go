@@ -0,0 +1,70 @@
+package core
+
+import (
+	"container/list"
+	"sync"
+)
+
+// LRUCache is a fixed size cache, safe for concurrent use, which evicts the least recently used entries.
+type LRUCache[K comparable, V any] struct {
+	mu      sync.Mutex
+	size    int
+	items   map[K]*list.Element
+	history *list.List
+}
+
+type lruCacheItem[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+func NewLRUCache[K comparable, V any](size int) *LRUCache[K, V] {
+	return &LRUCache[K, V]{
+		size:    size,
+		items:   make(map[K]*list.Element, size),
+		history: list.New(),
+	}
+}
+
+func (cache *LRUCache[K, V]) Get(key K) (V, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if element, ok := cache.items[key]; ok {
+		cache.history.MoveToFront(element)
+		return element.Value.(*lruCacheItem[K, V]).value, true
+	}
+
+	var zero V
+	return zero, false
+}
+
+func (cache *LRUCache[K, V]) Add(key K, value V) {
+	if cache.size <= 0 {
+		return
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if element, ok := cache.items[key]; ok {
+		cache.history.MoveToFront(element)
+		element.Value.(*lruCacheItem[K, V]).value = value
+		return
+	}
+
+	cache.items[key] = cache.history.PushFront(&lruCacheItem[K, V]{key: key, value: value})
+	if cache.history.Len() > cache.size {
+		oldest := cache.history.Back()
+		cache.history.Remove(oldest)
+		delete(cache.items, oldest.Value.(*lruCacheItem[K, V]).key)
+	}
+}
+
+func (cache *LRUCache[K, V]) Purge() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.items = make(map[K]*list.Element, cache.size)
+	cache.history.Init()
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// numberUnits contains multipliers of units accepted by ParseNumber :
+// durations are converted to seconds and sizes to bytes (1KB = 1024B).
+var numberUnits = map[string]float64{
+	"ns": 1e-9, "us": 1e-6, "µs": 1e-6, "ms": 1e-3, "s": 1, "sec": 1, "min": 60, "h": 3600, "d": 86400,
+	"b": 1, "k": 1 << 10, "kb": 1 << 10, "kib": 1 << 10, "mb": 1 << 20, "mib": 1 << 20,
+	"g": 1 << 30, "gb": 1 << 30, "gib": 1 << 30, "t": 1 << 40, "tb": 1 << 40, "tib": 1 << 40,
+	"%": 1,
+}
+
+// numberAmbiguousUnit is either minutes or megabytes (units are not case sensitive), so it is rejected.
+const numberAmbiguousUnit = "m"
+
+// ParseNumber parse a number with an optional unit, e.g. "42", "-1.5", "250ms", "2 s", "10KB" or "1.2GiB".
+// Durations are converted to seconds and sizes to bytes, so "1500ms" equals "1.5s" and "2KB" equals "2048".
+// Unit "m" is ambiguous and rejected, use "min" for minutes or "MB" for megabytes.
+func ParseNumber(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+
+	end := 0
+	for end < len(value) && strings.IndexByte("+-0123456789.eE", value[end]) >= 0 {
+		// exponent is only allowed after a digit and when followed by a digit or a sign
+		if (value[end] == 'e' || value[end] == 'E') &&
+			(end == 0 || end+1 >= len(value) || strings.IndexByte("+-0123456789", value[end+1]) < 0) {
+			break
+		}
+		end++
+	}
+
+	number, err := strconv.ParseFloat(value[:end], 64)
+	if err != nil {
+		return 0, fmt.Errorf("\"%s\" is not a number", value)
+	}
+
+	unit := strings.ToLower(strings.TrimSpace(value[end:]))
+	if unit == "" {
+		return number, nil
+	}
+	if unit == numberAmbiguousUnit {
+		return 0, fmt.Errorf("\"%s\" has an ambiguous unit \"%s\", use \"min\" or \"MB\"", value, value[end:])
+	}
+	multiplier, ok := numberUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("\"%s\" has an unknown unit \"%s\"", value, value[end:])
+	}
+
+	return number * multiplier, nil
+}
@@ -0,0 +1,51 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestParseNumber(t *testing.T) {
+	tests := map[string]struct {
+		value    string
+		expected float64
+		hasError bool
+	}{
+		"integer":                {value: "42", expected: 42},
+		"negative decimal":       {value: "-1.5", expected: -1.5},
+		"exponent":               {value: "1.5e3", expected: 1500},
+		"spaces":                 {value: " 2 s ", expected: 2},
+		"milliseconds":           {value: "250ms", expected: 0.25},
+		"microseconds":           {value: "1500µs", expected: 0.0015},
+		"nanoseconds":            {value: "2ns", expected: 2e-9},
+		"minutes":                {value: "10min", expected: 600},
+		"hours":                  {value: "1.5h", expected: 5400},
+		"days":                   {value: "2d", expected: 172800},
+		"bytes":                  {value: "512B", expected: 512},
+		"kilobytes":              {value: "10KB", expected: 10240},
+		"kilobytes short":        {value: "2k", expected: 2048},
+		"megabytes":              {value: "10MB", expected: 10 << 20},
+		"mebibytes":              {value: "1MiB", expected: 1 << 20},
+		"gigabytes":              {value: "1.2GiB", expected: 1.2 * (1 << 30)},
+		"terabytes":              {value: "1T", expected: 1 << 40},
+		"percent":                {value: "95%", expected: 95},
+		"unit case":              {value: "2Kb", expected: 2048},
+		"ambiguous lower case":   {value: "10m", hasError: true},
+		"ambiguous upper case":   {value: "10M", hasError: true},
+		"unknown unit":           {value: "10 apples", hasError: true},
+		"exponent without digit": {value: "e5", hasError: true},
+		"word":                   {value: "fast", hasError: true},
+		"empty":                  {value: "", hasError: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			number, err := ParseNumber(test.value)
+			if (err != nil) != test.hasError {
+				t.Fatalf("ParseNumber(%q) error = %v, want error %t", test.value, err, test.hasError)
+			}
+			if number != test.expected {
+				t.Errorf("ParseNumber(%q) = %v, want %v", test.value, number, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"strings"
+)
+
+// NormalizeSQL returns a query fingerprint : literals are replaced by "?", lists of literals
+// are collapsed to "?+", comments are removed and whitespaces are collapsed.
+// e.g. "SELECT * FROM t WHERE id IN (1, 2) AND name = 'bob'" => "SELECT * FROM t WHERE id IN (?+) AND name = ?"
+func NormalizeSQL(query string) string {
+	var builder strings.Builder
+	builder.Grow(len(query))
+
+	space := false
+	writeSpace := func() {
+		if space && builder.Len() > 0 {
+			builder.WriteByte(' ')
+		}
+		space = false
+	}
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			// line comment
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+			space = true
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			// block comment
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + 3
+			}
+			space = true
+		case c == '\'':
+			// string literal, backslash and doubled quotes escape the quote
+			// (double quotes are kept as they quote identifiers in PostgreSQL)
+			for i++; i < len(query); i++ {
+				if query[i] == '\\' {
+					i++
+					continue
+				}
+				if query[i] == c {
+					if i+1 < len(query) && query[i+1] == c {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			writeSpace()
+			builder.WriteByte('?')
+		case isSQLDigit(c) && (i == 0 || !isSQLIdentifierByte(query[i-1])):
+			// numeric literal (including decimals, exponents and hexadecimal), digits of identifiers are kept
+			for i+1 < len(query) && (isSQLIdentifierByte(query[i+1]) || query[i+1] == '.') {
+				i++
+			}
+			writeSpace()
+			builder.WriteByte('?')
+		default:
+			writeSpace()
+			builder.WriteByte(c)
+		}
+	}
+
+	normalized := strings.TrimRight(builder.String(), "; ")
+	return collapseSQLLists(normalized)
+}
+
+// collapseSQLLists replace lists of at least 2 placeholders, e.g. "(?, ?, ?)", by "(?+)".
+// A single placeholder (e.g. "COALESCE(?)") is kept.
+func collapseSQLLists(query string) string {
+	var builder strings.Builder
+	builder.Grow(len(query))
+
+	for i := 0; i < len(query); i++ {
+		if query[i] != '(' {
+			builder.WriteByte(query[i])
+			continue
+		}
+		end := strings.IndexByte(query[i:], ')')
+		if end < 0 {
+			builder.WriteString(query[i:])
+			break
+		}
+		if isSQLPlaceholderList(query[i+1 : i+end]) {
+			builder.WriteString("(?+)")
+			i += end
+			continue
+		}
+		builder.WriteByte('(')
+	}
+
+	return builder.String()
+}
+
+func isSQLPlaceholderList(content string) bool {
+	items := strings.Split(content, ",")
+	if len(items) < 2 {
+		return false
+	}
+	for _, item := range items {
+		if strings.TrimSpace(item) != "?" {
+			return false
+		}
+	}
+	return true
+}
+
+func isSQLDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isSQLIdentifierByte(c byte) bool {
+	return c == '_' || c == '$' || isSQLDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestNormalizeSQL(t *testing.T) {
+	tests := map[string]struct {
+		query    string
+		expected string
+	}{
+		"string and number": {
+			query:    "SELECT * FROM users WHERE id = 42 AND name = 'bob'",
+			expected: "SELECT * FROM users WHERE id = ? AND name = ?",
+		},
+		"number after space": {
+			query:    "SELECT 1",
+			expected: "SELECT ?",
+		},
+		"limit and offset": {
+			query:    "SELECT * FROM orders LIMIT 10 OFFSET 20",
+			expected: "SELECT * FROM orders LIMIT ? OFFSET ?",
+		},
+		"between": {
+			query:    "SELECT * FROM orders WHERE total BETWEEN 1 AND 5",
+			expected: "SELECT * FROM orders WHERE total BETWEEN ? AND ?",
+		},
+		"digits of identifiers": {
+			query:    "SELECT t1.col2, $1 FROM table_3 t1",
+			expected: "SELECT t1.col2, $1 FROM table_3 t1",
+		},
+		"decimal, exponent and hexadecimal": {
+			query:    "UPDATE t SET a = 1.5, b = 2e10, c = 0xFF, d = -3",
+			expected: "UPDATE t SET a = ?, b = ?, c = ?, d = -?",
+		},
+		"list": {
+			query:    "SELECT * FROM t WHERE id IN (1, 2,3) AND status IN ('a', 'b')",
+			expected: "SELECT * FROM t WHERE id IN (?+) AND status IN (?+)",
+		},
+		"list of one item": {
+			query:    "SELECT * FROM t WHERE id IN (1)",
+			expected: "SELECT * FROM t WHERE id IN (?)",
+		},
+		"single argument call": {
+			query:    "SELECT COALESCE(5), COALESCE(a, 0) FROM t",
+			expected: "SELECT COALESCE(?), COALESCE(a, ?) FROM t",
+		},
+		"multi-row insert": {
+			query:    "INSERT INTO t (a, b) VALUES (1, 'x'), (2, 'y');",
+			expected: "INSERT INTO t (a, b) VALUES (?+), (?+)",
+		},
+		"escaped quotes": {
+			query:    `SELECT 'it''s', 'a\'b', "Column" FROM t`,
+			expected: `SELECT ?, ?, "Column" FROM t`,
+		},
+		"comments and whitespaces": {
+			query:    "SELECT a -- comment 1\n  FROM /* block 2 */ t\n\tWHERE b = 3",
+			expected: "SELECT a FROM t WHERE b = ?",
+		},
+		"unterminated comment": {
+			query:    "SELECT a FROM t /* comment",
+			expected: "SELECT a FROM t",
+		},
+		"unterminated list": {
+			query:    "SELECT a FROM t WHERE id IN (1, 2",
+			expected: "SELECT a FROM t WHERE id IN (?, ?",
+		},
+		"empty": {query: "", expected: ""},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if normalized := NormalizeSQL(test.query); normalized != test.expected {
+				t.Errorf("NormalizeSQL(%q) = %q, want %q", test.query, normalized, test.expected)
+			}
+		})
+	}
+}
+
+func TestCollapseSQLLists(t *testing.T) {
+	tests := map[string]struct {
+		query    string
+		expected string
+	}{
+		"list":              {query: "IN (?, ?, ?)", expected: "IN (?+)"},
+		"list of two items": {query: "IN (?,?)", expected: "IN (?+)"},
+		"single item":       {query: "COALESCE(?)", expected: "COALESCE(?)"},
+		"not placeholders":  {query: "f(a, ?)", expected: "f(a, ?)"},
+		"empty item":        {query: "f(?, , ?)", expected: "f(?, , ?)"},
+		"empty":             {query: "NOW()", expected: "NOW()"},
+		"nested":            {query: "f((?, ?), ?)", expected: "f((?+), ?)"},
+		"several lists":     {query: "(?, ?), (?, ?)", expected: "(?+), (?+)"},
+		"unterminated":      {query: "IN (?, ?", expected: "IN (?, ?"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if collapsed := collapseSQLLists(test.query); collapsed != test.expected {
+				t.Errorf("collapseSQLLists(%q) = %q, want %q", test.query, collapsed, test.expected)
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ require (
 	github.com/creasty/defaults v1.8.0
 	github.com/go-playground/validator/v10 v10.25.0
 	github.com/nxadm/tail v1.4.11
+	github.com/oschwald/maxminddb-golang v1.13.1
 	github.com/satori/go.uuid v1.2.0
 	gopkg.in/mail.v2 v2.3.1
 	gopkg.in/yaml.v3 v3.0.1